@@ -5,14 +5,24 @@ import (
 	"api/internal/config"
 	"api/internal/database"
 	"api/internal/handler"
+	"api/internal/health"
+	"api/internal/logging"
+	"api/internal/media"
 	"api/internal/middleware"
 	"api/internal/monitoring"
 	"api/internal/repository"
 	"api/internal/service"
+	"api/internal/storage"
+	"api/internal/tracing"
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
-	"runtime"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +31,13 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// Информация о сборке, задается при сборке через -ldflags "-X main.buildVersion=..."
+var (
+	buildVersion = "dev"
+	buildCommit  = "unknown"
+	buildTime    = "unknown"
+)
+
 // @title User API Service
 // @version 1.0
 // @description API для регистрации, авторизации и управления пользователями
@@ -33,43 +50,52 @@ import (
 func main() {
 
 	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
-		cfg := config.LoadConfig()
-		db, err := database.NewDatabase(cfg)
-		if err != nil {
-			fmt.Println("FAILED: Cannot connect to database")
-			os.Exit(1)
-		}
-		defer db.Close()
-
-		if err := db.WriteDB.Ping(); err != nil {
-			fmt.Println("FAILED: Write database not accessible")
-			os.Exit(1)
+		probe := "ready"
+		if len(os.Args) > 2 {
+			probe = os.Args[2]
 		}
-
-		if err := db.ReadDB.Ping(); err != nil {
-			fmt.Println("FAILED: Read database not accessible")
-			os.Exit(1)
-		}
-
-		fmt.Println("OK: All systems operational")
-		os.Exit(0)
+		os.Exit(runHealthcheck(config.LoadConfig(), probe))
 	}
 
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Initialize logging
+	if _, err := logging.Init(cfg.Logging); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize logging:", err)
+		os.Exit(1)
+	}
+
+	// Метрики среды выполнения и информация о сборке
+	monitoring.RegisterRuntimeCollectors()
+	monitoring.SetBuildInfo(buildVersion, buildCommit, buildTime)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
 	// Initialize database
 	db, err := database.NewDatabase(cfg)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
 	}
 	defer db.Close()
 
 	// Initialize Redis cache
+	// Соединение устанавливается лениво: если Redis недоступен, запросы идут в БД,
+	// а подключение восстанавливается в фоне
 	redisCache, err := cache.NewRedisCache(cfg)
 	if err != nil {
-		log.Printf("Warning: Failed to connect to Redis: %v", err)
-		log.Println("Continuing without cache...")
+		slog.Warn("Failed to configure Redis, continuing without cache", "error", err)
 		// Можно создать заглушку или продолжить без кэша
 		redisCache = nil
 	} else {
@@ -78,32 +104,76 @@ func main() {
 
 	// Initialize tables
 	if err := database.InitTables(db); err != nil {
-		log.Fatal("Failed to initialize tables:", err)
+		fatal("Failed to initialize tables", err)
 	}
 
-	// Initialize services
-	cacheService := service.NewCacheService(redisCache)
-
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(db.WriteDB, db.ReadDB)
-	friendRepo := repository.NewFriendRepository(db.WriteDB, db.ReadDB)
-	postRepo := repository.NewPostRepository(db.WriteDB, db.ReadDB)
+	userRepo := repository.NewUserRepository(db)
+	friendRepo := repository.NewFriendRepository(db)
+	postRepo := repository.NewPostRepository(db)
+	statsRepo := repository.NewStatsRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	reactionRepo := repository.NewReactionRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+
+	// Хранилище файлов вложений. Клиентам файлы отдает /media этого сервера по подписанным ссылкам
+	blobStore, err := storage.NewBlobStore(cfg.Media)
+	if err != nil {
+		fatal("Failed to initialize media store", err)
+	}
+	mediaURL := cfg.Media.PublicURL
+	if mediaURL == "" {
+		mediaURL = strings.TrimSuffix(cfg.ServerPath, "/") + "/media"
+	}
+	mediaSigner := media.NewURLSigner(mediaURL, cfg.Media.URLSecret, cfg.Media.URLTTL)
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, cfg.JWTSecret)
-	friendService := service.NewFriendService(friendRepo, userRepo)
+	// Фоновые задачи сервисов, завершения которых ждем при остановке
+	backgroundTasks := service.NewBackgroundTasks()
+	auditService := service.NewAuditService(auditRepo, backgroundTasks, cfg.Audit)
+	cacheService := service.NewCacheService(redisCache, auditService)
+	friendService := service.NewFriendService(friendRepo, userRepo, cacheService, auditService, backgroundTasks)
 	// postService := service.NewPostService(postRepo)
-	postService := service.NewPostService(postRepo, cacheService)
+	reactionService := service.NewReactionService(reactionRepo, redisCache, backgroundTasks, cfg.Reactions)
+	attachmentService := service.NewAttachmentService(attachmentRepo, blobStore, mediaSigner, backgroundTasks, cfg.Media)
+	postService := service.NewPostService(postRepo, friendService, cacheService, auditService, reactionService, attachmentService, backgroundTasks, cfg.SoftDelete)
+	userService := service.NewUserService(userRepo, postService, reactionService, auditService, cfg.JWTSecret, cfg.SoftDelete)
+	purgeService := service.NewPurgeService(postRepo, userRepo, attachmentService, backgroundTasks, cfg.SoftDelete)
+	commentService := service.NewCommentService(commentRepo, postService, auditService)
+	activityService := service.NewActivityService(redisCache, backgroundTasks, cfg.CacheWarmup.ActiveWindow)
+	warmupService := service.NewCacheWarmupService(postService, activityService, backgroundTasks, cfg.CacheWarmup)
+	businessMetricsService := service.NewBusinessMetricsService(statsRepo, backgroundTasks, cfg.BusinessMetricsInterval)
+
+	// Проверки зависимостей для пробы готовности
+	checker, err := health.NewChecker(cfg.Health)
+	if err != nil {
+		fatal("Failed to configure health checks", err)
+	}
+	checker.Register(health.CheckWriteDB, health.WriteDB(db))
+	checker.Register(health.CheckReadDB, health.ReadDB(db))
+	checker.Register(health.CheckReplicaLag, health.ReplicaLag(db, cfg.ReadDBMaxLag))
+	if redisCache != nil {
+		checker.Register(health.CheckRedis, health.Redis(redisCache))
+	}
 
 	// Initialize handlers
 	userHandler := handler.NewUserHandler(userService)
 	friendHandler := handler.NewFriendHandler(friendService)
 	postHandler := handler.NewPostHandler(postService)
+	commentHandler := handler.NewCommentHandler(commentService)
+	attachmentHandler := handler.NewAttachmentHandler(postService, cfg.Media.MaxSize)
 	searchHandler := handler.NewSearchHandler(userService)
-	cacheHandler := handler.NewCacheHandler(cacheService, postService)
+	healthHandler := handler.NewHealthHandler(checker)
+	auditHandler := handler.NewAuditHandler(auditService)
+	cacheHandler := handler.NewCacheHandler(cacheService, postService, warmupService, redisCache != nil && cfg.CacheWarmup.OnInvalidate)
 
 	// Create Gin router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(middleware.TracingMiddleware())
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.ClientInfoMiddleware())
+	router.Use(middleware.AccessLogMiddleware())
 
 	router.Use(middleware.PrometheusMiddleware())
 
@@ -111,6 +181,10 @@ func main() {
 		router.Use(middleware.CORSWithConfig(cfg.CORSAllowedOrigins))
 	}
 
+	// Ответы с ошибками пишутся ближе к обработчикам, чтобы метрики и лог доступа видели итоговый статус
+	router.Use(middleware.ErrorMiddleware(), gin.CustomRecovery(middleware.RecoveryHandler))
+	router.NoRoute(middleware.NoRouteHandler)
+
 	// Metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
@@ -133,18 +207,25 @@ func main() {
 	{
 		public.POST("/register", userHandler.Register)
 		public.POST("/login", userHandler.Login)
+		public.POST("/account/restore", userHandler.RestoreAccount)
+
+		// Файлы вложений без заголовка авторизации: доступ дает подписанная ссылка
+		public.GET("/media/:id", attachmentHandler.GetMedia)
+		public.GET("/media/:id/thumbnail", attachmentHandler.GetMediaThumbnail)
 	}
 
 	// Protected routes
 	protected := router.Group(cfg.ServerPath)
 	protected.Use(middleware.AuthMiddleware(userService))
+	protected.Use(middleware.ActivityMiddleware(activityService))
 	{
 
 		// User routes
 		protected.GET("/users", userHandler.GetAllUsers)
 		protected.GET("/users/:id", userHandler.GetUser)
 		protected.GET("/profile", userHandler.GetProfile)
-		// protected.PUT("/profile", userHandler.UpdateProfile)
+		protected.PUT("/profile", userHandler.UpdateProfile)
+		protected.DELETE("/profile", userHandler.DeleteProfile)
 
 		// Friend routes
 		protected.POST("/friend/add", friendHandler.AddFriend)
@@ -156,9 +237,21 @@ func main() {
 		protected.POST("/post/create", postHandler.CreatePost)
 		protected.GET("/post/get/:id", postHandler.GetPost)
 		protected.PUT("/post/update/:id", postHandler.UpdatePost)
+		protected.GET("/post/:id/revisions", postHandler.GetPostRevisions)
 		protected.DELETE("/post/delete/:id", postHandler.DeletePost)
+		protected.POST("/post/restore/:id", postHandler.RestorePost)
 		protected.GET("/posts", postHandler.GetUserPosts)
 		protected.GET("/post/feed", postHandler.GetFeed)
+		protected.POST("/post/:id/like", postHandler.LikePost)
+		protected.DELETE("/post/:id/like", postHandler.UnlikePost)
+		protected.POST("/post/:id/attachments", attachmentHandler.UploadAttachment)
+		protected.DELETE("/attachment/delete/:id", attachmentHandler.DeleteAttachment)
+
+		// Comment routes
+		protected.POST("/post/:id/comments", commentHandler.CreateComment)
+		protected.GET("/post/:id/comments", commentHandler.GetComments)
+		protected.PUT("/comment/update/:id", commentHandler.UpdateComment)
+		protected.DELETE("/comment/delete/:id", commentHandler.DeleteComment)
 
 		// Search routes
 		protected.GET("/user/search", searchHandler.SearchUsers)
@@ -170,76 +263,150 @@ func main() {
 
 	}
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		writeDBErr := db.WriteDB.Ping()
-		readDBErr := db.ReadDB.Ping()
+	// Admin routes
+	admin := router.Group(cfg.ServerPath + "/admin")
+	admin.Use(middleware.AuthMiddleware(userService))
+	admin.Use(middleware.AdminMiddleware(cfg.AdminUserIDs))
+	{
+		admin.GET("/audit", auditHandler.GetAuditLog)
+	}
 
-		status := "ok"
-		if writeDBErr != nil || readDBErr != nil {
-			status = "degraded"
-		}
+	// Health check endpoints
+	router.GET("/health/live", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
+	// Прежний путь для совместимости, отвечает как проба готовности
+	router.GET("/health", healthHandler.Ready)
 
-		// Проверяем Redis если он подключен
-		redisStatus := "not_configured"
-		var redisStats map[string]interface{}
-
-		if redisCache != nil {
-			if err := redisCache.HealthCheck(); err != nil {
-				redisStatus = "disconnected"
-			} else {
-				redisStatus = "connected"
-				redisStats = redisCache.GetStats()
-			}
-		}
+	// Прогреваем кэш для недавно активных пользователей
+	if redisCache != nil && cfg.CacheWarmup.OnStartup {
+		warmupService.StartWarmUpActiveUsers(context.Background(), service.WarmupTriggerStartup)
+	}
 
-		response := gin.H{
-			"status":   status,
-			"write_db": map[string]interface{}{"connected": writeDBErr == nil},
-			"read_db":  map[string]interface{}{"connected": readDBErr == nil},
-			"redis":    redisStatus,
-		}
+	// Бизнес-метрики обновляются периодически по данным реплики
+	businessMetricsService.Start(context.Background())
+	// Устаревшие записи журнала аудита удаляются по сроку хранения
+	auditService.StartPruning(context.Background())
+	// Устаревшие записи об активности пользователей удаляются периодически
+	activityService.StartPruning(context.Background())
+	// Счетчики реакций из Redis периодически сохраняются в PostgreSQL
+	reactionService.StartFlushing(context.Background())
+	// Удаленные посты и пользователи окончательно удаляются по сроку хранения
+	purgeService.StartPurging(context.Background())
 
-		if redisStats != nil {
-			response["redis_stats"] = redisStats
-		}
+	// Start server
+	srv := &http.Server{
+		Addr:              ":" + cfg.ServerPort,
+		Handler:           router,
+		ReadTimeout:       cfg.ServerTimeouts.Read,
+		ReadHeaderTimeout: cfg.ServerTimeouts.ReadHeader,
+		WriteTimeout:      cfg.ServerTimeouts.Write,
+		IdleTimeout:       cfg.ServerTimeouts.Idle,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
 
-		c.JSON(200, response)
-	})
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	slog.Info("Server starting",
+		"version", buildVersion,
+		"commit", buildCommit,
+		"port", cfg.ServerPort,
+		"redis_cache", redisCache != nil,
+		"metrics", fmt.Sprintf("http://localhost:%s/metrics", cfg.ServerPort),
+	)
+	if cfg.ServerSwagger == "enabled" {
+		slog.Info("Swagger UI available", "url", fmt.Sprintf("http://localhost:%s/swagger/index.html", cfg.ServerPort))
+	}
 
-	// Start server
+	select {
+	case err := <-serverErr:
+		fatal("Failed to start server", err)
+	case <-signalCtx.Done():
+	}
+	// Повторный сигнал завершает процесс без ожидания
+	stopSignals()
 
-	log.Printf("Server starting on port %s", cfg.ServerPort)
-	if redisCache != nil {
-		log.Printf("Redis cache: ENABLED")
-	} else {
-		log.Printf("Redis cache: DISABLED")
+	shutdown(srv, backgroundTasks, checker, cfg.ServerTimeouts)
+}
+
+// shutdown плавно останавливает сервер: снимает готовность, ждет, пока балансировщик
+// исключит экземпляр, затем дожидается завершения текущих запросов и фоновых задач.
+// Соединения с БД, Redis и экспорт трасс закрываются после возврата из main.
+func shutdown(srv *http.Server, tasks *service.BackgroundTasks, checker *health.Checker, timeouts config.ServerTimeouts) {
+	slog.Info("Shutting down",
+		"delay", timeouts.ShutdownDelay.String(),
+		"timeout", timeouts.Shutdown.String(),
+	)
+	checker.SetShuttingDown()
+	time.Sleep(timeouts.ShutdownDelay)
+
+	ctx := context.Background()
+	if timeouts.Shutdown > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeouts.Shutdown)
+		defer cancel()
 	}
-	log.Printf("Metrics available at: http://localhost:%s/metrics", cfg.ServerPort)
-	if cfg.ServerSwagger == "enabled" {
-		log.Printf("Swagger UI available at: http://localhost:%s/swagger/index.html", cfg.ServerPort)
+
+	start := time.Now()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("HTTP server did not finish in-flight requests", "error", err)
+		_ = srv.Close()
 	}
-	if err := router.Run(":" + cfg.ServerPort); err != nil {
-		log.Fatal("Failed to start server:", err)
+	if err := tasks.Shutdown(ctx); err != nil {
+		slog.Error("Background tasks did not finish and were canceled", "error", err)
 	}
+
+	slog.Info("Server stopped", "duration_ms", time.Since(start).Milliseconds())
 }
 
-// collectSystemMetrics собирает системные метрики
-func collectSystemMetrics() {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+// fatal пишет ошибку в лог и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// runHealthcheck проверяет запущенный сервер через /health/ready или /health/live
+// и возвращает код завершения для HEALTHCHECK контейнера (в образе нет curl и wget)
+func runHealthcheck(cfg *config.Config, probe string) int {
+	if probe != "ready" && probe != "live" {
+		fmt.Println("FAILED: Unknown probe", probe, "(expected ready or live)")
+		return 1
+	}
+
+	client := &http.Client{Timeout: cfg.Health.CheckTimeout + 3*time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%s/health/%s", cfg.ServerPort, probe))
+	if err != nil {
+		fmt.Println("FAILED: Server not accessible:", err)
+		return 1
+	}
+	defer resp.Body.Close()
 
-	for range ticker.C {
-		// Количество goroutines
-		monitoring.GoroutinesCount.Set(float64(runtime.NumGoroutine()))
+	var report health.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		fmt.Println("FAILED: Invalid health response:", err)
+		return 1
+	}
 
-		// Использование памяти
-		var m runtime.MemStats
-		runtime.ReadMemStats(&m)
+	code := 0
+	switch {
+	case resp.StatusCode != http.StatusOK:
+		fmt.Println("FAILED: Service is not ready, status", report.Status)
+		code = 1
+	case report.Status == health.StatusDegraded:
+		fmt.Println("DEGRADED: Non-critical checks failed")
+	default:
+		fmt.Println("OK: All systems operational")
+	}
 
-		monitoring.MemoryUsage.WithLabelValues("alloc").Set(float64(m.Alloc))
-		monitoring.MemoryUsage.WithLabelValues("sys").Set(float64(m.Sys))
-		monitoring.MemoryUsage.WithLabelValues("heap_alloc").Set(float64(m.HeapAlloc))
-		monitoring.MemoryUsage.WithLabelValues("heap_sys").Set(float64(m.HeapSys))
+	for _, check := range report.Checks {
+		if check.Status != health.CheckPassed {
+			fmt.Printf("  %s (critical: %t): %s\n", check.Name, check.Critical, check.Error)
+		}
 	}
+	return code
 }
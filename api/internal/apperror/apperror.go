@@ -0,0 +1,132 @@
+package apperror
+
+import "errors"
+
+// Виды доменных ошибок. Проверяются через errors.Is, например errors.Is(err, apperror.ErrNotFound).
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrForbidden          = errors.New("forbidden")
+	ErrConflict           = errors.New("conflict")
+	ErrBadRequest         = errors.New("bad request")
+	ErrValidation         = errors.New("validation failed")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Стабильные коды ошибок, на которые может опираться клиент.
+// Коды не переименовываются: при изменении смысла добавляется новый код.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidToken       = "invalid_token"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeAdminRequired      = "admin_required"
+	CodeRouteNotFound      = "route_not_found"
+
+	CodeUserNotFound      = "user_not_found"
+	CodeUserAlreadyExists = "user_already_exists"
+	CodeAccountDeleted    = "account_deleted"
+	CodeAccountNotDeleted = "account_not_deleted"
+
+	CodePostNotFound   = "post_not_found"
+	CodePostForbidden  = "post_forbidden"
+	CodePostNotDeleted = "post_not_deleted"
+	CodePostModified   = "post_modified"
+
+	CodeRestoreWindowExpired = "restore_window_expired"
+
+	CodeCommentNotFound      = "comment_not_found"
+	CodeCommentForbidden     = "comment_forbidden"
+	CodeInvalidParentComment = "invalid_parent_comment"
+	CodeInvalidCursor        = "invalid_cursor"
+
+	CodeAttachmentNotFound  = "attachment_not_found"
+	CodeAttachmentForbidden = "attachment_forbidden"
+	CodeAttachmentLimit     = "attachment_limit_exceeded"
+	CodeMediaURLInvalid     = "media_url_invalid"
+
+	CodeFriendshipNotFound = "friendship_not_found"
+	CodeAlreadyFriends     = "already_friends"
+	CodeCannotFriendSelf   = "cannot_friend_self"
+
+	CodeRequestCanceled = "request_canceled"
+	CodeTimeout         = "timeout"
+	CodeInternal        = "internal_error"
+)
+
+// Error доменная ошибка: вид (ErrNotFound, ErrForbidden, ...), стабильный код
+// и сообщение, которое можно показать клиенту
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	// Исходная ошибка, если есть; клиенту не показывается
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap позволяет проверять и вид ошибки, и исходную ошибку через errors.Is/As
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// Wrap возвращает копию ошибки с исходной ошибкой err
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// NotFound объект не найден
+func NotFound(code, message string) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message}
+}
+
+// Forbidden действие запрещено текущему пользователю
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: message}
+}
+
+// Conflict действие противоречит текущему состоянию (объект уже существует и т.п.)
+func Conflict(code, message string) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+// BadRequest запрос не удалось разобрать (некорректный JSON, нечисловой ID в пути и т.п.)
+func BadRequest(code, message string) *Error {
+	return &Error{Kind: ErrBadRequest, Code: code, Message: message}
+}
+
+// Validation запрос разобран, но данные не прошли проверку
+func Validation(code, message string) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message}
+}
+
+// Unauthorized пользователь не аутентифицирован
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: ErrUnauthorized, Code: code, Message: message}
+}
+
+// PreconditionFailed объект изменился после того, как клиент его прочитал
+func PreconditionFailed(code, message string) *Error {
+	return &Error{Kind: ErrPreconditionFailed, Code: code, Message: message}
+}
+
+// As возвращает доменную ошибку из цепочки err
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается вместо обращения к Redis, пока предохранитель разомкнут
+var ErrCircuitOpen = errors.New("redis circuit breaker is open")
+
+// BreakerState состояние предохранителя
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker размыкается после threshold ошибок подряд и в течение openTimeout
+// сразу возвращает ErrCircuitOpen. После таймаута пропускает один пробный запрос.
+type CircuitBreaker struct {
+	mu sync.Mutex
+
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight bool
+
+	threshold     int
+	openTimeout   time.Duration
+	onStateChange func(from, to BreakerState)
+}
+
+func NewCircuitBreaker(threshold int, openTimeout time.Duration, onStateChange func(from, to BreakerState)) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &CircuitBreaker{
+		state:         BreakerClosed,
+		threshold:     threshold,
+		openTimeout:   openTimeout,
+		onStateChange: onStateChange,
+	}
+}
+
+// Allow проверяет, можно ли выполнить запрос
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		b.halfOpenInFlight = true
+		return nil
+	case BreakerHalfOpen:
+		if b.halfOpenInFlight {
+			return ErrCircuitOpen
+		}
+		b.halfOpenInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success фиксирует успешный запрос и замыкает предохранитель
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.halfOpenInFlight = false
+	if b.state != BreakerClosed {
+		b.setState(BreakerClosed)
+	}
+}
+
+// Recover замыкает разомкнутый или полуоткрытый предохранитель после успешной
+// фоновой проверки. Счетчик ошибок замкнутого предохранителя не сбрасывается:
+// успешный PING не означает, что запросы перестали завершаться ошибками.
+func (b *CircuitBreaker) Recover() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerClosed {
+		return
+	}
+	b.failures = 0
+	b.halfOpenInFlight = false
+	b.setState(BreakerClosed)
+}
+
+// Failure фиксирует ошибку запроса
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.halfOpenInFlight = false
+	b.failures++
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.trip()
+	case BreakerClosed:
+		if b.failures >= b.threshold {
+			b.trip()
+		}
+	case BreakerOpen:
+		// Продлеваем период размыкания
+		b.openedAt = time.Now()
+	}
+}
+
+// Release освобождает пробный запрос, результат которого не говорит о состоянии Redis
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.halfOpenInFlight = false
+}
+
+// Trip принудительно размыкает предохранитель
+func (b *CircuitBreaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trip()
+}
+
+// State возвращает текущее состояние
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) trip() {
+	b.openedAt = time.Now()
+	if b.state != BreakerOpen {
+		b.setState(BreakerOpen)
+	}
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	from := b.state
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
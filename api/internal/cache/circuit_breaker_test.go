@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type breakerStep struct {
+	op      string // allow, success, failure, release, trip, recover
+	wantErr error  // только для allow
+	want    BreakerState
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	// При нулевом таймауте разомкнутый предохранитель сразу пропускает пробный запрос,
+	// за час тесты не успевают дождаться пробного запроса
+	const never = time.Hour
+
+	tests := []struct {
+		name        string
+		threshold   int
+		openTimeout time.Duration
+		steps       []breakerStep
+	}{
+		{
+			name:        "trips after threshold consecutive failures",
+			threshold:   3,
+			openTimeout: never,
+			steps: []breakerStep{
+				{op: "failure", want: BreakerClosed},
+				{op: "failure", want: BreakerClosed},
+				{op: "failure", want: BreakerOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, want: BreakerOpen},
+			},
+		},
+		{
+			name:        "threshold below one is treated as one",
+			threshold:   0,
+			openTimeout: never,
+			steps: []breakerStep{
+				{op: "failure", want: BreakerOpen},
+			},
+		},
+		{
+			name:        "success resets failure count",
+			threshold:   3,
+			openTimeout: never,
+			steps: []breakerStep{
+				{op: "failure", want: BreakerClosed},
+				{op: "failure", want: BreakerClosed},
+				{op: "success", want: BreakerClosed},
+				{op: "failure", want: BreakerClosed},
+				{op: "failure", want: BreakerClosed},
+				{op: "failure", want: BreakerOpen},
+			},
+		},
+		{
+			name:        "half-open lets a single probe through",
+			threshold:   1,
+			openTimeout: 0,
+			steps: []breakerStep{
+				{op: "trip", want: BreakerOpen},
+				{op: "allow", want: BreakerHalfOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, want: BreakerHalfOpen},
+				{op: "success", want: BreakerClosed},
+				{op: "allow", want: BreakerClosed},
+				{op: "allow", want: BreakerClosed},
+			},
+		},
+		{
+			name:        "failed probe opens again",
+			threshold:   3,
+			openTimeout: 0,
+			steps: []breakerStep{
+				{op: "trip", want: BreakerOpen},
+				{op: "allow", want: BreakerHalfOpen},
+				{op: "failure", want: BreakerOpen},
+			},
+		},
+		{
+			name:        "released probe frees the slot",
+			threshold:   1,
+			openTimeout: 0,
+			steps: []breakerStep{
+				{op: "trip", want: BreakerOpen},
+				{op: "allow", want: BreakerHalfOpen},
+				{op: "release", want: BreakerHalfOpen},
+				{op: "allow", want: BreakerHalfOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, want: BreakerHalfOpen},
+			},
+		},
+		{
+			name:        "stays open until timeout",
+			threshold:   1,
+			openTimeout: never,
+			steps: []breakerStep{
+				{op: "trip", want: BreakerOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, want: BreakerOpen},
+				{op: "failure", want: BreakerOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, want: BreakerOpen},
+			},
+		},
+		{
+			name:        "recover closes an open breaker",
+			threshold:   1,
+			openTimeout: never,
+			steps: []breakerStep{
+				{op: "trip", want: BreakerOpen},
+				{op: "recover", want: BreakerClosed},
+				{op: "allow", want: BreakerClosed},
+			},
+		},
+		{
+			name:        "recover closes a half-open breaker and frees the probe",
+			threshold:   1,
+			openTimeout: 0,
+			steps: []breakerStep{
+				{op: "trip", want: BreakerOpen},
+				{op: "allow", want: BreakerHalfOpen},
+				{op: "recover", want: BreakerClosed},
+				{op: "allow", want: BreakerClosed},
+			},
+		},
+		{
+			name:        "recover keeps failure count of a closed breaker",
+			threshold:   2,
+			openTimeout: never,
+			steps: []breakerStep{
+				{op: "failure", want: BreakerClosed},
+				{op: "recover", want: BreakerClosed},
+				{op: "failure", want: BreakerOpen},
+			},
+		},
+		{
+			name:        "recover after reopening resets failure count",
+			threshold:   2,
+			openTimeout: never,
+			steps: []breakerStep{
+				{op: "failure", want: BreakerClosed},
+				{op: "failure", want: BreakerOpen},
+				{op: "recover", want: BreakerClosed},
+				{op: "failure", want: BreakerClosed},
+				{op: "failure", want: BreakerOpen},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(tt.threshold, tt.openTimeout, nil)
+
+			for i, step := range tt.steps {
+				var err error
+				switch step.op {
+				case "allow":
+					err = b.Allow()
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "release":
+					b.Release()
+				case "trip":
+					b.Trip()
+				case "recover":
+					b.Recover()
+				default:
+					t.Fatalf("step %d: unknown op %q", i, step.op)
+				}
+
+				if !errors.Is(err, step.wantErr) {
+					t.Fatalf("step %d (%s): err = %v, want %v", i, step.op, err, step.wantErr)
+				}
+				if got := b.State(); got != step.want {
+					t.Fatalf("step %d (%s): state = %s, want %s", i, step.op, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerStateChanges(t *testing.T) {
+	type transition struct{ from, to BreakerState }
+	var got []transition
+	b := NewCircuitBreaker(1, 0, func(from, to BreakerState) {
+		got = append(got, transition{from, to})
+	})
+
+	b.Failure()
+	_ = b.Allow()
+	b.Failure()
+	_ = b.Allow()
+	b.Success()
+	// Повторные успехи и Recover замкнутого предохранителя переходов не вызывают
+	b.Success()
+	b.Recover()
+
+	want := []transition{
+		{BreakerClosed, BreakerOpen},
+		{BreakerOpen, BreakerHalfOpen},
+		{BreakerHalfOpen, BreakerOpen},
+		{BreakerOpen, BreakerHalfOpen},
+		{BreakerHalfOpen, BreakerClosed},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("transitions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("transition %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
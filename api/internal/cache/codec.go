@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Первый байт каждой записи в кэше идентифицирует кодек.
+// Значения выбраны так, чтобы не пересекаться с первым символом JSON,
+// поэтому записи, сохраненные до появления заголовка, читаются как JSON.
+const (
+	codecIDJSON    byte = 0x01
+	codecIDMsgPack byte = 0x02
+
+	// flagCompressed выставляется в заголовке, если полезная нагрузка сжата gzip
+	flagCompressed byte = 0x80
+)
+
+// Codec сериализует значения для хранения в кэше
+type Codec interface {
+	ID() byte
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// jsonCodec кодек на основе encoding/json
+type jsonCodec struct{}
+
+func (jsonCodec) ID() byte     { return codecIDJSON }
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec кодек MessagePack, использует json-теги моделей
+type msgpackCodec struct{}
+
+func (msgpackCodec) ID() byte     { return codecIDMsgPack }
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+var codecs = map[byte]Codec{
+	codecIDJSON:    jsonCodec{},
+	codecIDMsgPack: msgpackCodec{},
+}
+
+// NewCodec возвращает кодек по имени из конфигурации
+func NewCodec(name string) (Codec, error) {
+	switch name {
+	case "", "json":
+		return jsonCodec{}, nil
+	case "msgpack":
+		return msgpackCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache codec: %s", name)
+	}
+}
+
+// encodeEntry сериализует значение и добавляет заголовок.
+// Если размер данных превышает threshold (и threshold > 0), данные сжимаются.
+func encodeEntry(codec Codec, threshold int, value interface{}) ([]byte, error) {
+	payload, err := codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	header := codec.ID()
+	if threshold > 0 && len(payload) > threshold {
+		compressed, err := gzipBytes(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress value: %w", err)
+		}
+		// Сжатие выгодно не всегда, храним меньший вариант
+		if len(compressed) < len(payload) {
+			payload = compressed
+			header |= flagCompressed
+		}
+	}
+
+	data := make([]byte, 0, len(payload)+1)
+	data = append(data, header)
+	return append(data, payload...), nil
+}
+
+// decodeEntry разбирает заголовок и десериализует значение.
+// Записи без заголовка считаются JSON (формат до появления кодеков).
+func decodeEntry(data []byte, dest interface{}) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty cache entry")
+	}
+
+	header := data[0]
+	codec, ok := codecs[header&^flagCompressed]
+	if !ok {
+		return jsonCodec{}.Unmarshal(data, dest)
+	}
+
+	payload := data[1:]
+	if header&flagCompressed != 0 {
+		var err error
+		payload, err = gunzipBytes(payload)
+		if err != nil {
+			return fmt.Errorf("failed to decompress value: %w", err)
+		}
+	}
+
+	return codec.Unmarshal(payload, dest)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -2,42 +2,67 @@ package cache
 
 import (
 	"api/internal/config"
-	"encoding/json"
+	"api/internal/logging"
+	"api/internal/monitoring"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrHashNotIncremented возвращается IncrementHashFields, если поля хэша не изменены
+var ErrHashNotIncremented = errors.New("hash fields not incremented")
+
+// hashSetAddAttempts число попыток добавить элемент в множество после изменения полей хэша
+const hashSetAddAttempts = 3
+
 type RedisCache struct {
 	wrapper *RedisWrapper
+
+	codec                Codec
+	compressionThreshold int
 }
 
 func NewRedisCache(cfg *config.Config) (*RedisCache, error) {
+	codec, err := NewCodec(cfg.Redis.Codec)
+	if err != nil {
+		return nil, err
+	}
+
 	wrapper, err := NewRedisWrapper(&cfg.Redis)
 	if err != nil {
 		return nil, err
 	}
 
 	return &RedisCache{
-		wrapper: wrapper,
+		wrapper:              wrapper,
+		codec:                codec,
+		compressionThreshold: cfg.Redis.CompressionThreshold,
 	}, nil
 }
 
 // Set сохраняет значение в кэш
-func (r *RedisCache) Set(key string, value interface{}, expiration time.Duration) error {
-	data, err := json.Marshal(value)
+func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	data, err := encodeEntry(r.codec, r.compressionThreshold, value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
 
-	return r.wrapper.Set(r.wrapper.ctx, key, data, expiration).Err()
+	if err := r.wrapper.Set(ctx, key, data, expiration).Err(); err != nil {
+		return err
+	}
+
+	monitoring.RecordCacheStoredBytes(cacheTypeFromKey(key), r.codec.Name(), len(data))
+	return nil
 }
 
 // Get получает значение из кэша
-func (r *RedisCache) Get(key string, dest interface{}) error {
-	data, err := r.wrapper.Get(r.wrapper.ctx, key).Bytes()
+func (r *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := r.wrapper.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return fmt.Errorf("cache miss")
@@ -45,7 +70,7 @@ func (r *RedisCache) Get(key string, dest interface{}) error {
 		return fmt.Errorf("failed to get from cache: %w", err)
 	}
 
-	if err := json.Unmarshal(data, dest); err != nil {
+	if err := decodeEntry(data, dest); err != nil {
 		return fmt.Errorf("failed to unmarshal value: %w", err)
 	}
 
@@ -53,45 +78,45 @@ func (r *RedisCache) Get(key string, dest interface{}) error {
 }
 
 // Delete удаляет ключ из кэша
-func (r *RedisCache) Delete(key string) error {
-	return r.wrapper.Del(r.wrapper.ctx, key).Err()
+func (r *RedisCache) Delete(ctx context.Context, key string) error {
+	return r.wrapper.Del(ctx, key).Err()
 }
 
 // DeleteByPattern удаляет ключи по паттерну
-func (r *RedisCache) DeleteByPattern(pattern string) error {
+func (r *RedisCache) DeleteByPattern(ctx context.Context, pattern string) error {
 	if r.wrapper.clientType == "cluster" {
-		log.Printf("Warning: DeleteByPattern in cluster mode may not work correctly for distributed keys")
+		logging.FromContext(ctx).Warn("DeleteByPattern in cluster mode may not work correctly for distributed keys", "pattern", pattern)
 	}
 
-	keys, err := r.wrapper.Keys(r.wrapper.ctx, pattern).Result()
+	keys, err := r.wrapper.Keys(ctx, pattern).Result()
 	if err != nil {
 		return fmt.Errorf("failed to get keys by pattern: %w", err)
 	}
 
 	if len(keys) > 0 {
-		return r.wrapper.Del(r.wrapper.ctx, keys...).Err()
+		return r.wrapper.Del(ctx, keys...).Err()
 	}
 
 	return nil
 }
 
 // SafeDeleteByPattern безопасное удаление по паттерну для кластера
-func (r *RedisCache) SafeDeleteByPattern(pattern string) error {
+func (r *RedisCache) SafeDeleteByPattern(ctx context.Context, pattern string) error {
 	if r.wrapper.clientType == "cluster" {
-		return r.deleteByPatternCluster(pattern)
+		return r.deleteByPatternCluster(ctx, pattern)
 	}
-	return r.DeleteByPattern(pattern)
+	return r.DeleteByPattern(ctx, pattern)
 }
 
 // deleteByPatternCluster реализует безопасное удаление для кластера
-func (r *RedisCache) deleteByPatternCluster(pattern string) error {
+func (r *RedisCache) deleteByPatternCluster(ctx context.Context, pattern string) error {
 	var cursor uint64
 	var allKeys []string
 
 	for {
 		var keys []string
 		var err error
-		keys, cursor, err = r.wrapper.Scan(r.wrapper.ctx, cursor, pattern, 100).Result()
+		keys, cursor, err = r.wrapper.Scan(ctx, cursor, pattern, 100).Result()
 		if err != nil {
 			return fmt.Errorf("failed to scan keys: %w", err)
 		}
@@ -112,8 +137,8 @@ func (r *RedisCache) deleteByPatternCluster(pattern string) error {
 			}
 
 			batch := allKeys[i:end]
-			if err := r.wrapper.Del(r.wrapper.ctx, batch...).Err(); err != nil {
-				log.Printf("Failed to delete batch %d-%d: %v", i, end, err)
+			if err := r.wrapper.Del(ctx, batch...).Err(); err != nil {
+				logging.FromContext(ctx).Warn("Failed to delete cache keys batch", "pattern", pattern, "from", i, "to", end, logging.Err(err))
 			}
 		}
 	}
@@ -122,8 +147,149 @@ func (r *RedisCache) deleteByPatternCluster(pattern string) error {
 }
 
 // Exists проверяет существование ключа
-func (r *RedisCache) Exists(key string) bool {
-	return r.wrapper.Exists(r.wrapper.ctx, key).Val() > 0
+func (r *RedisCache) Exists(ctx context.Context, key string) bool {
+	return r.wrapper.Exists(ctx, key).Val() > 0
+}
+
+// AddToSortedSet добавляет (или обновляет) элемент упорядоченного множества
+func (r *RedisCache) AddToSortedSet(ctx context.Context, key, member string, score float64) error {
+	return r.wrapper.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+// GetSortedSetByScore возвращает элементы с score в диапазоне [min, max] по убыванию score.
+// Читает с реплики, если включено чтение с реплик Sentinel: результат может немного отставать.
+func (r *RedisCache) GetSortedSetByScore(ctx context.Context, key string, min, max float64, limit int64) ([]string, error) {
+	return r.wrapper.Replica().ZRevRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min:   strconv.FormatFloat(min, 'f', -1, 64),
+		Max:   strconv.FormatFloat(max, 'f', -1, 64),
+		Count: limit,
+	}).Result()
+}
+
+// TrimSortedSetByScore удаляет элементы с score меньше max
+func (r *RedisCache) TrimSortedSetByScore(ctx context.Context, key string, max float64) error {
+	return r.wrapper.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatFloat(max, 'f', -1, 64)).Err()
+}
+
+// IncrementHashFields изменяет целочисленные поля хэша на deltas и добавляет
+// member в множество setKey (например, в список ключей для обработки) за одно обращение.
+// Если поля не изменены, возвращается ошибка ErrHashNotIncremented. Если поля изменены,
+// а member не добавлен, добавление повторяется: без него изменения не будут обработаны.
+func (r *RedisCache) IncrementHashFields(ctx context.Context, key string, deltas map[string]int64, setKey, member string) error {
+	incrCmds := make(map[string]*redis.IntCmd, len(deltas))
+	var addCmd *redis.IntCmd
+	_, err := r.wrapper.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for field, delta := range deltas {
+			incrCmds[field] = pipe.HIncrBy(ctx, key, field, delta)
+		}
+		addCmd = pipe.SAdd(ctx, setKey, member)
+		return nil
+	})
+	if addCmd == nil {
+		// Команды не отправлены (например, разомкнут предохранитель)
+		return fmt.Errorf("%w: %v", ErrHashNotIncremented, err)
+	}
+
+	if incrErr := r.revertPartialIncrements(ctx, key, deltas, incrCmds); incrErr != nil {
+		return fmt.Errorf("%w: %v", ErrHashNotIncremented, incrErr)
+	}
+
+	err = addCmd.Err()
+	for attempt := 1; err != nil && attempt < hashSetAddAttempts; attempt++ {
+		err = r.wrapper.SAdd(ctx, setKey, member).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("add %s to %s: %w", member, setKey, err)
+	}
+	return nil
+}
+
+// revertPartialIncrements возвращает ошибку, если хотя бы одно поле не изменено,
+// и откатывает изменения остальных полей, чтобы хэш остался в исходном состоянии
+func (r *RedisCache) revertPartialIncrements(ctx context.Context, key string, deltas map[string]int64, cmds map[string]*redis.IntCmd) error {
+	var failed error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			failed = cmd.Err()
+			break
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+
+	_, err := r.wrapper.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for field, cmd := range cmds {
+			if cmd.Err() == nil {
+				pipe.HIncrBy(ctx, key, field, -deltas[field])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to revert hash field increments", "key", key, logging.Err(err))
+	}
+	return failed
+}
+
+// GetHashCounters возвращает целочисленные поля хэшей keys; отсутствующим ключам соответствуют пустые map
+func (r *RedisCache) GetHashCounters(ctx context.Context, keys []string) ([]map[string]int64, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(keys))
+	_, err := r.wrapper.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.HGetAll(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]int64, len(keys))
+	for i, cmd := range cmds {
+		result[i] = make(map[string]int64, len(cmd.Val()))
+		for field, value := range cmd.Val() {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			result[i][field] = n
+		}
+	}
+	return result, nil
+}
+
+// DeleteKeys удаляет ключи по одному в конвейере, чтобы не упираться в ограничение
+// кластера на ключи из разных слотов в одной команде
+func (r *RedisCache) DeleteKeys(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := r.wrapper.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// AddToSet добавляет элементы в множество
+func (r *RedisCache) AddToSet(ctx context.Context, key string, members ...string) error {
+	args := make([]interface{}, len(members))
+	for i, member := range members {
+		args[i] = member
+	}
+	return r.wrapper.SAdd(ctx, key, args...).Err()
+}
+
+// PopFromSet извлекает из множества до count случайных элементов
+func (r *RedisCache) PopFromSet(ctx context.Context, key string, count int64) ([]string, error) {
+	return r.wrapper.SPopN(ctx, key, count).Result()
 }
 
 // Close закрывает соединение с Redis
@@ -132,8 +298,8 @@ func (r *RedisCache) Close() error {
 }
 
 // HealthCheck проверяет состояние Redis
-func (r *RedisCache) HealthCheck() error {
-	return r.wrapper.Ping(r.wrapper.ctx).Err()
+func (r *RedisCache) HealthCheck(ctx context.Context) error {
+	return r.wrapper.Ping(ctx).Err()
 }
 
 // GetStats возвращает статистику Redis
@@ -142,6 +308,8 @@ func (r *RedisCache) GetStats() map[string]interface{} {
 
 	return map[string]interface{}{
 		"client_type": r.wrapper.clientType,
+		"codec":       r.codec.Name(),
+		"breaker":     r.wrapper.BreakerState().String(),
 		"hits":        stats.Hits,
 		"misses":      stats.Misses,
 		"timeouts":    stats.Timeouts,
@@ -151,6 +319,24 @@ func (r *RedisCache) GetStats() map[string]interface{} {
 	}
 }
 
+// BreakerState возвращает состояние предохранителя Redis (closed, half_open, open)
+func (r *RedisCache) BreakerState() string {
+	return r.wrapper.BreakerState().String()
+}
+
+// GetCodecName возвращает имя кодека, используемого для записи
+func (r *RedisCache) GetCodecName() string {
+	return r.codec.Name()
+}
+
+// cacheTypeFromKey возвращает тип кэша по префиксу ключа (feed, posts, user, ...)
+func cacheTypeFromKey(key string) string {
+	if i := strings.Index(key, ":"); i > 0 {
+		return key[:i]
+	}
+	return "other"
+}
+
 // GetClientType возвращает тип клиента
 func (r *RedisCache) GetClientType() string {
 	return r.wrapper.GetClientType()
@@ -2,10 +2,11 @@ package cache
 
 import (
 	"api/internal/config"
+	"api/internal/monitoring"
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -16,15 +17,58 @@ type RedisWrapper struct {
 	clientType string
 	client     interface{} // Может быть *redis.Client или *redis.ClusterClient
 	ctx        context.Context
+
+	// Обертка над репликами Sentinel для чтений, допускающих отставание (nil - реплики не используются)
+	replica *RedisWrapper
+
+	// Предохранитель: пока он разомкнут, операции сразу завершаются с ErrCircuitOpen
+	breaker           *CircuitBreaker
+	reconnectInterval time.Duration
+	stop              chan struct{}
 }
 
 func NewRedisWrapper(cfg *config.RedisConfig) (*RedisWrapper, error) {
 	var client interface{}
 	var clientType string
+	var replicaClient *redis.Client
 
 	ctx := context.Background()
 
-	if cfg.ClusterMode {
+	if cfg.SentinelMode {
+		// Sentinel конфигурация: мастер определяется через Sentinel
+		if len(cfg.SentinelAddrs) == 0 {
+			return nil, fmt.Errorf("sentinel addresses are required in sentinel mode")
+		}
+		if cfg.SentinelMasterName == "" {
+			return nil, fmt.Errorf("sentinel master name is required in sentinel mode")
+		}
+
+		opts := &redis.FailoverOptions{
+			MasterName:       cfg.SentinelMasterName,
+			SentinelAddrs:    cfg.SentinelAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			PoolSize:         cfg.PoolSize,
+			MinIdleConns:     cfg.MinIdleConns,
+			DialTimeout:      cfg.DialTimeout,
+			ReadTimeout:      cfg.ReadTimeout,
+			WriteTimeout:     cfg.WriteTimeout,
+		}
+
+		// Все команды идут на мастер: после удаления ключа реплика еще какое-то время
+		// возвращает старое значение. С реплик читается только через Replica().
+		client = redis.NewFailoverClient(opts)
+		if cfg.SentinelReplicaReads {
+			replicaOpts := *opts
+			replicaOpts.ReplicaOnly = true
+			replicaClient = redis.NewFailoverClient(&replicaOpts)
+		}
+
+		clientType = "sentinel"
+		slog.Info("Redis Sentinel client initialized",
+			"master", cfg.SentinelMasterName, "sentinels", cfg.SentinelAddrs, "replica_reads", cfg.SentinelReplicaReads)
+	} else if cfg.ClusterMode {
 		// Кластерная конфигурация
 		if len(cfg.ClusterNodes) == 0 {
 			return nil, fmt.Errorf("cluster nodes are required in cluster mode")
@@ -35,11 +79,14 @@ func NewRedisWrapper(cfg *config.RedisConfig) (*RedisWrapper, error) {
 			Password:     cfg.Password,
 			PoolSize:     cfg.PoolSize,
 			MinIdleConns: cfg.MinIdleConns,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
 		})
 
 		client = clusterClient
 		clientType = "cluster"
-		log.Printf("Redis Cluster client initialized with nodes: %v", cfg.ClusterNodes)
+		slog.Info("Redis Cluster client initialized", "nodes", cfg.ClusterNodes)
 	} else {
 		// Standalone конфигурация
 		addr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
@@ -49,113 +96,396 @@ func NewRedisWrapper(cfg *config.RedisConfig) (*RedisWrapper, error) {
 			DB:           cfg.DB,
 			PoolSize:     cfg.PoolSize,
 			MinIdleConns: cfg.MinIdleConns,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
 		})
 
 		client = standaloneClient
 		clientType = "standalone"
-		log.Printf("Redis Standalone client initialized: %s", addr)
+		slog.Info("Redis Standalone client initialized", "addr", addr)
 	}
 
-	// Проверяем соединение
-	var pingErr error
+	// Команды, выполняемые в рамках запроса, попадают в его трассу
+	hook := tracingHook{clientType: clientType}
 	switch c := client.(type) {
 	case *redis.Client:
-		pingErr = c.Ping(ctx).Err()
+		c.AddHook(hook)
 	case *redis.ClusterClient:
-		pingErr = c.Ping(ctx).Err()
-	default:
-		return nil, fmt.Errorf("unknown client type")
+		c.AddHook(hook)
+	}
+	if replicaClient != nil {
+		replicaClient.AddHook(hook)
+	}
+
+	w := &RedisWrapper{
+		clientType:        clientType,
+		client:            client,
+		ctx:               ctx,
+		reconnectInterval: cfg.ReconnectInterval,
+		stop:              make(chan struct{}),
 	}
+	w.breaker = NewCircuitBreaker(cfg.BreakerFailureThreshold, cfg.BreakerOpenTimeout, w.onBreakerStateChange)
+	monitoring.SetRedisBreakerState(BreakerClosed.String())
 
-	if pingErr != nil {
-		return nil, fmt.Errorf("failed to connect to Redis (%s): %w", clientType, pingErr)
+	if replicaClient != nil {
+		// Предохранитель общий: без мастера реплики не получают изменений
+		w.replica = &RedisWrapper{
+			clientType: clientType,
+			client:     replicaClient,
+			ctx:        ctx,
+			breaker:    w.breaker,
+		}
+	}
+
+	// Проверяем соединение. Недоступность Redis при старте не является ошибкой:
+	// предохранитель размыкается, а фоновая проверка восстановит соединение.
+	if err := w.ping(ctx); err != nil {
+		slog.Warn("Redis is not available, will retry in background", "client_type", clientType, "error", err)
+		w.breaker.Trip()
+	} else {
+		slog.Info("Redis connection established", "client_type", clientType)
 	}
 
-	log.Printf("Redis %s connection established successfully", strings.ToUpper(clientType))
-	return &RedisWrapper{
-		clientType: clientType,
-		client:     client,
-		ctx:        ctx,
-	}, nil
+	if w.reconnectInterval > 0 {
+		go w.monitor()
+	}
+
+	return w, nil
+}
+
+// monitor периодически проверяет соединение и замыкает предохранитель,
+// как только Redis снова становится доступен
+func (w *RedisWrapper) monitor() {
+	ticker := time.NewTicker(w.reconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(w.ctx, w.reconnectInterval)
+			err := w.ping(ctx)
+			cancel()
+
+			if err != nil {
+				w.breaker.Failure()
+				continue
+			}
+			w.breaker.Recover()
+		}
+	}
+}
+
+func (w *RedisWrapper) onBreakerStateChange(from, to BreakerState) {
+	level := slog.LevelWarn
+	if to == BreakerClosed {
+		level = slog.LevelInfo
+	}
+	slog.Log(context.Background(), level, "Redis circuit breaker state changed", "from", from.String(), "to", to.String())
+	monitoring.RecordRedisBreakerTransition(to.String())
+}
+
+// record передает результат операции предохранителю
+func (w *RedisWrapper) record(err error) {
+	switch {
+	case err == nil, errors.Is(err, redis.Nil):
+		w.breaker.Success()
+	case errors.Is(err, context.Canceled):
+		// Запрос отменен клиентом, о состоянии Redis это ничего не говорит
+		w.breaker.Release()
+	default:
+		w.breaker.Failure()
+	}
+}
+
+// ping проверяет соединение в обход предохранителя
+func (w *RedisWrapper) ping(ctx context.Context) error {
+	switch c := w.client.(type) {
+	case *redis.Client:
+		return c.Ping(ctx).Err()
+	case *redis.ClusterClient:
+		return c.Ping(ctx).Err()
+	default:
+		return fmt.Errorf("unknown client type")
+	}
 }
 
 // Универсальные методы для работы с клиентом
 
 func (w *RedisWrapper) Get(ctx context.Context, key string) *redis.StringCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewStringCmd(ctx, "get", key)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.StringCmd
 	switch c := w.client.(type) {
 	case *redis.Client:
-		return c.Get(ctx, key)
+		cmd = c.Get(ctx, key)
 	case *redis.ClusterClient:
-		return c.Get(ctx, key)
+		cmd = c.Get(ctx, key)
 	default:
 		return nil
 	}
+
+	w.record(cmd.Err())
+	return cmd
 }
 
 func (w *RedisWrapper) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewStatusCmd(ctx, "set", key)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.StatusCmd
 	switch c := w.client.(type) {
 	case *redis.Client:
-		return c.Set(ctx, key, value, expiration)
+		cmd = c.Set(ctx, key, value, expiration)
 	case *redis.ClusterClient:
-		return c.Set(ctx, key, value, expiration)
+		cmd = c.Set(ctx, key, value, expiration)
 	default:
 		return nil
 	}
+
+	w.record(cmd.Err())
+	return cmd
 }
 
 func (w *RedisWrapper) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewIntCmd(ctx, "del")
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.IntCmd
 	switch c := w.client.(type) {
 	case *redis.Client:
-		return c.Del(ctx, keys...)
+		cmd = c.Del(ctx, keys...)
 	case *redis.ClusterClient:
-		return c.Del(ctx, keys...)
+		cmd = c.Del(ctx, keys...)
 	default:
 		return nil
 	}
+
+	w.record(cmd.Err())
+	return cmd
 }
 
 func (w *RedisWrapper) Exists(ctx context.Context, keys ...string) *redis.IntCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewIntCmd(ctx, "exists")
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.IntCmd
 	switch c := w.client.(type) {
 	case *redis.Client:
-		return c.Exists(ctx, keys...)
+		cmd = c.Exists(ctx, keys...)
 	case *redis.ClusterClient:
-		return c.Exists(ctx, keys...)
+		cmd = c.Exists(ctx, keys...)
 	default:
 		return nil
 	}
+
+	w.record(cmd.Err())
+	return cmd
 }
 
 func (w *RedisWrapper) Keys(ctx context.Context, pattern string) *redis.StringSliceCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewStringSliceCmd(ctx, "keys", pattern)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.StringSliceCmd
 	switch c := w.client.(type) {
 	case *redis.Client:
-		return c.Keys(ctx, pattern)
+		cmd = c.Keys(ctx, pattern)
 	case *redis.ClusterClient:
-		return c.Keys(ctx, pattern)
+		cmd = c.Keys(ctx, pattern)
 	default:
 		return nil
 	}
+
+	w.record(cmd.Err())
+	return cmd
 }
 
 func (w *RedisWrapper) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewScanCmd(ctx, nil, "scan", cursor)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.ScanCmd
 	switch c := w.client.(type) {
 	case *redis.Client:
-		return c.Scan(ctx, cursor, match, count)
+		cmd = c.Scan(ctx, cursor, match, count)
 	case *redis.ClusterClient:
-		return c.Scan(ctx, cursor, match, count)
+		cmd = c.Scan(ctx, cursor, match, count)
 	default:
 		return nil
 	}
+
+	w.record(cmd.Err())
+	return cmd
+}
+
+func (w *RedisWrapper) ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewIntCmd(ctx, "zadd", key)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.IntCmd
+	switch c := w.client.(type) {
+	case *redis.Client:
+		cmd = c.ZAdd(ctx, key, members...)
+	case *redis.ClusterClient:
+		cmd = c.ZAdd(ctx, key, members...)
+	default:
+		return nil
+	}
+
+	w.record(cmd.Err())
+	return cmd
+}
+
+func (w *RedisWrapper) ZRevRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewStringSliceCmd(ctx, "zrevrangebyscore", key)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.StringSliceCmd
+	switch c := w.client.(type) {
+	case *redis.Client:
+		cmd = c.ZRevRangeByScore(ctx, key, opt)
+	case *redis.ClusterClient:
+		cmd = c.ZRevRangeByScore(ctx, key, opt)
+	default:
+		return nil
+	}
+
+	w.record(cmd.Err())
+	return cmd
+}
+
+func (w *RedisWrapper) ZRemRangeByScore(ctx context.Context, key, min, max string) *redis.IntCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewIntCmd(ctx, "zremrangebyscore", key, min, max)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.IntCmd
+	switch c := w.client.(type) {
+	case *redis.Client:
+		cmd = c.ZRemRangeByScore(ctx, key, min, max)
+	case *redis.ClusterClient:
+		cmd = c.ZRemRangeByScore(ctx, key, min, max)
+	default:
+		return nil
+	}
+
+	w.record(cmd.Err())
+	return cmd
+}
+
+func (w *RedisWrapper) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewIntCmd(ctx, "sadd", key)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.IntCmd
+	switch c := w.client.(type) {
+	case *redis.Client:
+		cmd = c.SAdd(ctx, key, members...)
+	case *redis.ClusterClient:
+		cmd = c.SAdd(ctx, key, members...)
+	default:
+		return nil
+	}
+
+	w.record(cmd.Err())
+	return cmd
+}
+
+func (w *RedisWrapper) SPopN(ctx context.Context, key string, count int64) *redis.StringSliceCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewStringSliceCmd(ctx, "spop", key, count)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.StringSliceCmd
+	switch c := w.client.(type) {
+	case *redis.Client:
+		cmd = c.SPopN(ctx, key, count)
+	case *redis.ClusterClient:
+		cmd = c.SPopN(ctx, key, count)
+	default:
+		return nil
+	}
+
+	w.record(cmd.Err())
+	return cmd
+}
+
+// Pipelined выполняет команды fn одним обращением к Redis
+// (в кластере - одним обращением к каждому узлу)
+func (w *RedisWrapper) Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	if err := w.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	var cmds []redis.Cmder
+	var err error
+	switch c := w.client.(type) {
+	case *redis.Client:
+		cmds, err = c.Pipelined(ctx, fn)
+	case *redis.ClusterClient:
+		cmds, err = c.Pipelined(ctx, fn)
+	default:
+		return nil, fmt.Errorf("unknown client type")
+	}
+
+	w.record(err)
+	return cmds, err
 }
 
 func (w *RedisWrapper) Ping(ctx context.Context) *redis.StatusCmd {
+	if err := w.breaker.Allow(); err != nil {
+		cmd := redis.NewStatusCmd(ctx, "ping")
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	var cmd *redis.StatusCmd
 	switch c := w.client.(type) {
 	case *redis.Client:
-		return c.Ping(ctx)
+		cmd = c.Ping(ctx)
 	case *redis.ClusterClient:
-		return c.Ping(ctx)
+		cmd = c.Ping(ctx)
 	default:
 		return nil
 	}
+
+	w.record(cmd.Err())
+	return cmd
 }
 
 func (w *RedisWrapper) PoolStats() *redis.PoolStats {
@@ -170,6 +500,14 @@ func (w *RedisWrapper) PoolStats() *redis.PoolStats {
 }
 
 func (w *RedisWrapper) Close() error {
+	close(w.stop)
+
+	if w.replica != nil {
+		if err := w.replica.client.(*redis.Client).Close(); err != nil {
+			slog.Warn("Failed to close Redis replica client", "error", err)
+		}
+	}
+
 	switch c := w.client.(type) {
 	case *redis.Client:
 		return c.Close()
@@ -180,6 +518,16 @@ func (w *RedisWrapper) Close() error {
 	}
 }
 
+// Replica возвращает обертку для чтений, которым допустимо отставание от мастера.
+// Если чтение с реплик Sentinel не включено, возвращает саму обертку.
+// Для инвалидации и чтений сразу после записи не используется.
+func (w *RedisWrapper) Replica() *RedisWrapper {
+	if w.replica == nil {
+		return w
+	}
+	return w.replica
+}
+
 func (w *RedisWrapper) GetClientType() string {
 	return w.clientType
 }
@@ -187,3 +535,8 @@ func (w *RedisWrapper) GetClientType() string {
 func (w *RedisWrapper) GetContext() context.Context {
 	return w.ctx
 }
+
+// BreakerState возвращает состояние предохранителя
+func (w *RedisWrapper) BreakerState() BreakerState {
+	return w.breaker.State()
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"api/internal/tracing"
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook создает дочерние спаны для команд Redis, выполняемых в рамках запроса
+type tracingHook struct {
+	clientType string
+}
+
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := tracing.StartChild(ctx, "redis "+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameRedis,
+				semconv.DBOperationName(cmd.Name()),
+				semconv.DBQueryText(cmdText(cmd)),
+				attribute.String("redis.client_type", h.clientType),
+			),
+		)
+		defer span.End()
+
+		err := next(ctx, cmd)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := tracing.StartChild(ctx, "redis pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameRedis,
+				semconv.DBOperationName("pipeline"),
+				attribute.String("redis.client_type", h.clientType),
+			),
+		)
+		defer span.End()
+
+		err := next(ctx, cmds)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	// Промах кэша - не ошибка
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// cmdText возвращает команду с ключом без значений, чтобы не выгружать данные кэша в трассы
+func cmdText(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return strings.ToUpper(cmd.Name())
+	}
+	if key, ok := args[1].(string); ok {
+		return strings.ToUpper(cmd.Name()) + " " + key
+	}
+	return strings.ToUpper(cmd.Name())
+}
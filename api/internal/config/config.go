@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type RedisConfig struct {
@@ -17,9 +18,163 @@ type RedisConfig struct {
 	ClusterMode  bool
 	ClusterNodes []string // ["host1:port1", "host2:port2", ...]
 
+	// Sentinel конфигурация (имеет приоритет над ClusterMode)
+	SentinelMode         bool
+	SentinelMasterName   string
+	SentinelAddrs        []string // ["host1:26379", "host2:26379", ...]
+	SentinelPassword     string
+	SentinelReplicaReads bool // Чтения, допускающие отставание, идут на реплики
+
 	// Общие настройки
 	PoolSize     int
 	MinIdleConns int
+
+	// Таймауты операций (короткие, чтобы при проблемах с Redis быстро уходить в БД)
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// Предохранитель и фоновое переподключение
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+	ReconnectInterval       time.Duration
+
+	// Сериализация значений: json или msgpack
+	Codec string
+	// Порог в байтах, выше которого значения сжимаются (0 - без сжатия)
+	CompressionThreshold int
+}
+
+// ServerTimeouts таймауты HTTP сервера и параметры плавной остановки (0 - без таймаута)
+type ServerTimeouts struct {
+	Read       time.Duration
+	ReadHeader time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+
+	// Пауза между снятием готовности и остановкой приема запросов,
+	// чтобы балансировщик успел исключить экземпляр
+	ShutdownDelay time.Duration
+	// Сколько ждать завершения текущих запросов и фоновых задач при остановке
+	Shutdown time.Duration
+}
+
+// HealthConfig настройки пробы готовности
+type HealthConfig struct {
+	// Таймаут каждой проверки зависимости
+	CheckTimeout time.Duration
+	// Проверки, отказ которых снимает готовность (write_db, read_db, replica_lag, redis).
+	// Отказ остальных только отмечается в ответе как деградация.
+	CriticalChecks []string
+}
+
+// AuditConfig настройки журнала аудита
+type AuditConfig struct {
+	// Срок хранения записей (0 - хранить бессрочно)
+	Retention time.Duration
+	// Интервал удаления устаревших записей
+	PruneInterval time.Duration
+}
+
+// ReactionsConfig настройки счетчиков реакций на посты
+type ReactionsConfig struct {
+	// Интервал сохранения счетчиков из Redis в PostgreSQL
+	FlushInterval time.Duration
+	// Количество постов, счетчики которых сохраняются одним запросом
+	FlushBatchSize int
+}
+
+// SoftDeleteConfig настройки мягкого удаления постов и пользователей
+type SoftDeleteConfig struct {
+	// Сколько после удаления пост или аккаунт можно восстановить
+	RestoreWindow time.Duration
+	// Через сколько после удаления данные удаляются окончательно (0 - не удалять).
+	// Не меньше окна восстановления
+	Retention time.Duration
+	// Интервал окончательного удаления
+	PurgeInterval time.Duration
+	// Количество постов или пользователей, удаляемых одним запросом
+	PurgeBatchSize int
+}
+
+// MediaConfig настройки вложений постов
+type MediaConfig struct {
+	// Хранилище файлов: local или s3
+	Store string
+	// Каталог файлов для локального хранилища
+	LocalDir string
+	// Адрес, по которому API отдает файлы; по умолчанию SERVER_PATH/media.
+	// Хранилище клиентам не открывается: файлы отдаются через API по подписанным ссылкам.
+	PublicURL string
+	// Срок действия подписанной ссылки на файл
+	URLTTL time.Duration
+	// Ключ подписи ссылок на файлы; по умолчанию JWT_SECRET
+	URLSecret string
+	// Максимальный размер файла в байтах
+	MaxSize int64
+	// Максимальное количество вложений у поста
+	MaxPerPost int
+	// Размер большей стороны миниатюры в пикселях
+	ThumbnailSize int
+	S3            S3Config
+}
+
+// S3Config настройки S3-совместимого хранилища (AWS S3, MinIO)
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// LoggingConfig настройки логирования
+type LoggingConfig struct {
+	// debug, info, warn или error
+	Level string
+	// json или text
+	Format string
+}
+
+// TracingConfig настройки трассировки OpenTelemetry.
+// Адрес коллектора задается стандартными переменными OTEL_EXPORTER_OTLP_*.
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	// Доля трасс, начатых этим сервисом, которые записываются (0..1)
+	SampleRatio float64
+}
+
+// QueryTimeouts таймауты запросов к БД по видам операций (0 - без таймаута)
+type QueryTimeouts struct {
+	Read   time.Duration
+	Write  time.Duration
+	Search time.Duration
+}
+
+// DBPoolConfig настройки пула соединений database/sql
+type DBPoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type CacheWarmupConfig struct {
+	// Прогрев кэша при старте сервиса
+	OnStartup bool
+	// Прогрев кэша пользователя после POST /cache/invalidate
+	OnInvalidate bool
+
+	// Количество пользователей, прогреваемых одновременно
+	Concurrency int
+	// Пользователь считается активным, если делал запросы за этот период
+	ActiveWindow time.Duration
+	// Максимальное количество пользователей для прогрева
+	MaxUsers int
+	// Количество страниц ленты и постов, прогреваемых для каждого пользователя
+	Pages    int
+	PageSize int
 }
 
 type Config struct {
@@ -28,22 +183,83 @@ type Config struct {
 	ServerSwagger string
 	JWTSecret     string
 
+	// ID пользователей с доступом к административным методам
+	AdminUserIDs []int
+
+	// Таймауты HTTP сервера
+	ServerTimeouts ServerTimeouts
+
 	// Database configurations
 	WriteDBHost     string
 	WriteDBPort     string
 	WriteDBName     string
 	WriteDBUser     string
 	WriteDBPassword string
+	WriteDBPool     DBPoolConfig
 
 	ReadDBHost     string
 	ReadDBPort     string
 	ReadDBName     string
 	ReadDBUser     string
 	ReadDBPassword string
+	ReadDBPool     DBPoolConfig
+
+	// TLS для соединений с БД: disable, require, verify-ca или verify-full
+	DBSSLMode     string
+	DBSSLRootCert string
+
+	// Список реплик для чтения (DSN в формате postgres://...).
+	// Если пуст, используется одна реплика из ReadDB* параметров.
+	ReadDBReplicas []string
+	// Стратегия выбора реплики: round_robin или least_latency
+	ReadDBBalancer string
+	// Интервал проверки состояния реплик. Ограничения отставания запросов меньше
+	// интервала выполнимы не всегда: до следующей проверки чтения идут на мастер.
+	ReadDBProbeInterval time.Duration
+	// Реплики с отставанием больше этого значения исключаются (0 - без ограничения)
+	ReadDBMaxLag time.Duration
+
+	// Таймауты запросов к БД
+	QueryTimeouts QueryTimeouts
+
+	// Порог для лога медленных запросов (0 - не писать)
+	DBSlowQueryThreshold time.Duration
+
+	// Согласованность чтения своих записей: off, primary или lsn
+	ReadConsistencyMode string
+	// Сколько после записи чтения пользователя учитывают эту запись
+	ReadConsistencyWindow time.Duration
 
 	// Redis configuration
 	Redis RedisConfig
 
+	// Cache warm-up configuration
+	CacheWarmup CacheWarmupConfig
+
+	// Интервал обновления бизнес-метрик (0 - не обновлять)
+	BusinessMetricsInterval time.Duration
+
+	// Health checks configuration
+	Health HealthConfig
+
+	// Audit log configuration
+	Audit AuditConfig
+
+	// Post reactions configuration
+	Reactions ReactionsConfig
+
+	// Soft delete configuration
+	SoftDelete SoftDeleteConfig
+
+	// Post attachments configuration
+	Media MediaConfig
+
+	// Logging configuration
+	Logging LoggingConfig
+
+	// Tracing configuration
+	Tracing TracingConfig
+
 	// CORS configuration
 	CORSAllowedOrigins []string
 }
@@ -58,23 +274,74 @@ func LoadConfig() *Config {
 		clusterNodes = []string{}
 	}
 
+	// Парсим список реплик для чтения
+	replicaDSNs := strings.Split(getEnv("READ_DB_REPLICAS", ""), ",")
+	if len(replicaDSNs) == 1 && replicaDSNs[0] == "" {
+		replicaDSNs = []string{}
+	}
+
+	// Парсим адреса Redis Sentinel
+	sentinelAddrs := strings.Split(getEnv("REDIS_SENTINEL_ADDRS", ""), ",")
+	if len(sentinelAddrs) == 1 && sentinelAddrs[0] == "" {
+		sentinelAddrs = []string{}
+	}
+
+	// Парсим список критичных проверок готовности; имена проверяются в health.NewChecker
+	criticalChecks := []string{}
+	for _, name := range strings.Split(getEnv("HEALTH_CRITICAL_CHECKS", "write_db"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			criticalChecks = append(criticalChecks, name)
+		}
+	}
+
 	return &Config{
 		ServerPort:    getEnv("SERVER_PORT", "8080"),
 		ServerPath:    getEnv("SERVER_PATH", "/api/v1"),
 		ServerSwagger: getEnv("SERVER_SWAGGER", "enabled"),
 		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key"),
 
+		AdminUserIDs: getEnvIntList("ADMIN_USER_IDS"),
+
+		ServerTimeouts: ServerTimeouts{
+			Read:          getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+			ReadHeader:    getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+			Write:         getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			Idle:          getEnvDuration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+			ShutdownDelay: getEnvDuration("SERVER_SHUTDOWN_DELAY", 5*time.Second),
+			Shutdown:      getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+		},
+
 		WriteDBHost:     getEnv("WRITE_DB_HOST", "localhost"),
 		WriteDBPort:     getEnv("WRITE_DB_PORT", "5432"),
 		WriteDBName:     getEnv("WRITE_DB_NAME", "root"),
 		WriteDBUser:     getEnv("WRITE_DB_USER", "root"),
 		WriteDBPassword: getEnv("WRITE_DB_PASSWORD", "password"),
+		WriteDBPool:     loadDBPoolConfig("WRITE_DB"),
 
 		ReadDBHost:     getEnv("READ_DB_HOST", "localhost"),
 		ReadDBPort:     getEnv("READ_DB_PORT", "5432"),
 		ReadDBName:     getEnv("READ_DB_NAME", "root"),
 		ReadDBUser:     getEnv("READ_DB_USER", "root"),
 		ReadDBPassword: getEnv("READ_DB_PASSWORD", "password"),
+		ReadDBPool:     loadDBPoolConfig("READ_DB"),
+
+		DBSSLMode:     getEnv("DB_SSLMODE", "disable"),
+		DBSSLRootCert: getEnv("DB_SSLROOTCERT", ""),
+
+		ReadDBReplicas:      replicaDSNs,
+		ReadDBBalancer:      getEnv("READ_DB_BALANCER", "round_robin"),
+		ReadDBProbeInterval: getEnvDuration("READ_DB_PROBE_INTERVAL", time.Second),
+		ReadDBMaxLag:        getEnvDuration("READ_DB_MAX_LAG", 30*time.Second),
+
+		QueryTimeouts: QueryTimeouts{
+			Read:   getEnvDuration("DB_READ_TIMEOUT", 3*time.Second),
+			Write:  getEnvDuration("DB_WRITE_TIMEOUT", 5*time.Second),
+			Search: getEnvDuration("DB_SEARCH_TIMEOUT", 5*time.Second),
+		},
+		DBSlowQueryThreshold: getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+
+		ReadConsistencyMode:   getEnv("DB_READ_CONSISTENCY_MODE", "primary"),
+		ReadConsistencyWindow: getEnvDuration("DB_READ_CONSISTENCY_WINDOW", 5*time.Second),
 
 		Redis: RedisConfig{
 			Host:         getEnv("REDIS_HOST", "localhost"),
@@ -83,14 +350,105 @@ func LoadConfig() *Config {
 			DB:           getEnvInt("REDIS_DB", 0),
 			ClusterMode:  getEnvBool("REDIS_CLUSTER_MODE", true),
 			ClusterNodes: clusterNodes,
+
+			SentinelMode:         getEnvBool("REDIS_SENTINEL_MODE", false),
+			SentinelMasterName:   getEnv("REDIS_SENTINEL_MASTER", ""),
+			SentinelAddrs:        sentinelAddrs,
+			SentinelPassword:     getEnv("REDIS_SENTINEL_PASSWORD", ""),
+			SentinelReplicaReads: getEnvBool("REDIS_SENTINEL_REPLICA_READS", false),
+
 			PoolSize:     getEnvInt("REDIS_POOL_SIZE", 10),
 			MinIdleConns: getEnvInt("REDIS_MIN_IDLE_CONNS", 5),
+
+			DialTimeout:  getEnvDuration("REDIS_DIAL_TIMEOUT", time.Second),
+			ReadTimeout:  getEnvDuration("REDIS_READ_TIMEOUT", 500*time.Millisecond),
+			WriteTimeout: getEnvDuration("REDIS_WRITE_TIMEOUT", 500*time.Millisecond),
+
+			BreakerFailureThreshold: getEnvInt("REDIS_BREAKER_FAILURE_THRESHOLD", 5),
+			BreakerOpenTimeout:      getEnvDuration("REDIS_BREAKER_OPEN_TIMEOUT", 10*time.Second),
+			ReconnectInterval:       getEnvDuration("REDIS_RECONNECT_INTERVAL", 5*time.Second),
+
+			Codec:                getEnv("REDIS_CODEC", "json"),
+			CompressionThreshold: getEnvInt("REDIS_COMPRESSION_THRESHOLD", 0),
+		},
+
+		CacheWarmup: CacheWarmupConfig{
+			OnStartup:    getEnvBool("CACHE_WARMUP_ON_STARTUP", true),
+			OnInvalidate: getEnvBool("CACHE_WARMUP_ON_INVALIDATE", false),
+			Concurrency:  getEnvInt("CACHE_WARMUP_CONCURRENCY", 4),
+			ActiveWindow: getEnvDuration("CACHE_WARMUP_ACTIVE_WINDOW", 24*time.Hour),
+			MaxUsers:     getEnvInt("CACHE_WARMUP_MAX_USERS", 1000),
+			Pages:        getEnvInt("CACHE_WARMUP_PAGES", 1),
+			PageSize:     getEnvInt("CACHE_WARMUP_PAGE_SIZE", 20),
+		},
+
+		BusinessMetricsInterval: getEnvDuration("BUSINESS_METRICS_INTERVAL", time.Minute),
+
+		Health: HealthConfig{
+			CheckTimeout:   getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			CriticalChecks: criticalChecks,
+		},
+
+		Audit: AuditConfig{
+			Retention:     getEnvDuration("AUDIT_RETENTION", 90*24*time.Hour),
+			PruneInterval: getEnvDuration("AUDIT_PRUNE_INTERVAL", time.Hour),
+		},
+
+		Reactions: ReactionsConfig{
+			FlushInterval:  getEnvDuration("REACTIONS_FLUSH_INTERVAL", 10*time.Second),
+			FlushBatchSize: getEnvInt("REACTIONS_FLUSH_BATCH_SIZE", 500),
+		},
+
+		SoftDelete: SoftDeleteConfig{
+			RestoreWindow:  getEnvDuration("SOFT_DELETE_RESTORE_WINDOW", 7*24*time.Hour),
+			Retention:      getEnvDuration("SOFT_DELETE_RETENTION", 30*24*time.Hour),
+			PurgeInterval:  getEnvDuration("SOFT_DELETE_PURGE_INTERVAL", time.Hour),
+			PurgeBatchSize: getEnvInt("SOFT_DELETE_PURGE_BATCH_SIZE", 500),
+		},
+
+		Media: MediaConfig{
+			Store:         getEnv("MEDIA_STORE", "local"),
+			LocalDir:      getEnv("MEDIA_LOCAL_DIR", "./data/media"),
+			PublicURL:     strings.TrimSuffix(getEnv("MEDIA_PUBLIC_URL", ""), "/"),
+			URLTTL:        getEnvDuration("MEDIA_URL_TTL", time.Hour),
+			URLSecret:     getEnv("MEDIA_URL_SECRET", getEnv("JWT_SECRET", "your-secret-key")),
+			MaxSize:       int64(getEnvInt("MEDIA_MAX_SIZE", 10<<20)),
+			MaxPerPost:    getEnvInt("MEDIA_MAX_PER_POST", 10),
+			ThumbnailSize: getEnvInt("MEDIA_THUMBNAIL_SIZE", 320),
+			S3: S3Config{
+				Endpoint:  strings.TrimSuffix(getEnv("S3_ENDPOINT", "http://localhost:9000"), "/"),
+				Region:    getEnv("S3_REGION", "us-east-1"),
+				Bucket:    getEnv("S3_BUCKET", "media"),
+				AccessKey: getEnv("S3_ACCESS_KEY", ""),
+				SecretKey: getEnv("S3_SECRET_KEY", ""),
+			},
+		},
+
+		Logging: LoggingConfig{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+
+		Tracing: TracingConfig{
+			Enabled:     getEnvBool("TRACING_ENABLED", false),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "api"),
+			SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
 		},
 
 		CORSAllowedOrigins: strings.Split(corsOrigins, ","),
 	}
 }
 
+// loadDBPoolConfig читает настройки пула с префиксом WRITE_DB или READ_DB
+func loadDBPoolConfig(prefix string) DBPoolConfig {
+	return DBPoolConfig{
+		MaxOpenConns:    getEnvInt(prefix+"_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    getEnvInt(prefix+"_MAX_IDLE_CONNS", 10),
+		ConnMaxLifetime: getEnvDuration(prefix+"_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: getEnvDuration(prefix+"_CONN_MAX_IDLE_TIME", 5*time.Minute),
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -107,6 +465,17 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvIntList читает список чисел через запятую; некорректные значения пропускаются
+func getEnvIntList(key string) []int {
+	var values []int
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if intValue, err := strconv.Atoi(strings.TrimSpace(item)); err == nil {
+			values = append(values, intValue)
+		}
+	}
+	return values
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value, exists := os.LookupEnv(key); exists {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -115,3 +484,21 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+	}
+	return defaultValue
+}
@@ -2,62 +2,276 @@ package database
 
 import (
 	"api/internal/config"
+	"api/internal/logging"
+	"api/internal/monitoring"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Database struct {
 	WriteDB *sql.DB
-	ReadDB  *sql.DB
+
+	// Реплики для чтения; при отсутствии исправных чтение идет с мастера
+	replicas *ReplicaSet
+
+	// Отметки о записях пользователей для чтения своих записей
+	sessions *SessionTracker
+
+	// Таймауты запросов по видам операций
+	timeouts config.QueryTimeouts
+
+	// Запросы дольше порога пишутся в лог медленных запросов (0 - не писать)
+	slowQueryThreshold time.Duration
+	writer             *DB
 }
 
+// Виды операций для таймаутов запросов
+const (
+	OpRead   = "read"
+	OpWrite  = "write"
+	OpSearch = "search"
+)
+
 func NewDatabase(cfg *config.Config) (*Database, error) {
-	writeDB, err := connectDB(
+	sessions, err := NewSessionTracker(cfg.ReadConsistencyMode, cfg.ReadConsistencyWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	writeDB, err := connectDB(buildDSN(
 		cfg.WriteDBHost,
 		cfg.WriteDBPort,
 		cfg.WriteDBName,
 		cfg.WriteDBUser,
 		cfg.WriteDBPassword,
-	)
+		cfg.DBSSLMode,
+		cfg.DBSSLRootCert,
+	), cfg.WriteDBPool)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to write database: %w", err)
 	}
+	slog.Info("Connected to write database", "host", cfg.WriteDBHost, "port", cfg.WriteDBPort)
+	monitoring.RegisterDBStats("write", writeDB)
+
+	// Список реплик: READ_DB_REPLICAS или одна реплика из READ_DB_*
+	replicaDSNs := cfg.ReadDBReplicas
+	if len(replicaDSNs) == 0 {
+		replicaDSNs = []string{buildDSN(
+			cfg.ReadDBHost,
+			cfg.ReadDBPort,
+			cfg.ReadDBName,
+			cfg.ReadDBUser,
+			cfg.ReadDBPassword,
+			cfg.DBSSLMode,
+			cfg.DBSSLRootCert,
+		)}
+	}
+
+	replicas := make([]*Replica, 0, len(replicaDSNs))
+	for i, dsn := range replicaDSNs {
+		name := replicaName(dsn, i)
+		if len(cfg.ReadDBReplicas) == 0 {
+			name = fmt.Sprintf("%s:%s", cfg.ReadDBHost, cfg.ReadDBPort)
+		}
+
+		// Недоступная при старте реплика не мешает запуску: она будет исключена
+		// из балансировки до тех пор, пока проверка не покажет, что она исправна
+		db, err := openDB(withSSLMode(dsn, cfg.DBSSLMode, cfg.DBSSLRootCert), cfg.ReadDBPool)
+		if err != nil {
+			writeDB.Close()
+			return nil, fmt.Errorf("failed to open read database %s: %w", name, err)
+		}
+		monitoring.RegisterDBStats(name, db)
+		replicas = append(replicas, &Replica{Name: name, DB: db})
+	}
 
-	readDB, err := connectDB(
-		cfg.ReadDBHost,
-		cfg.ReadDBPort,
-		cfg.ReadDBName,
-		cfg.ReadDBUser,
-		cfg.ReadDBPassword,
-	)
+	replicaSet, err := NewReplicaSet(writeDB, replicas, cfg.ReadDBBalancer, cfg.ReadDBProbeInterval, cfg.ReadDBMaxLag)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to read database: %w", err)
+		writeDB.Close()
+		return nil, err
+	}
+
+	for _, r := range replicas {
+		if r.Healthy() {
+			slog.Info("Connected to read replica", "replica", r.Name)
+		} else {
+			slog.Warn("Read replica is not available", "replica", r.Name)
+		}
 	}
 
 	return &Database{
-		WriteDB: writeDB,
-		ReadDB:  readDB,
+		WriteDB:  writeDB,
+		replicas: replicaSet,
+		sessions: sessions,
+		timeouts: cfg.QueryTimeouts,
+
+		slowQueryThreshold: cfg.DBSlowQueryThreshold,
+		writer:             newDB(writeDB, "primary", cfg.DBSlowQueryThreshold),
 	}, nil
 }
 
-func connectDB(host, port, dbname, user, password string) (*sql.DB, error) {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+// WithTimeout ограничивает контекст запроса таймаутом для вида операции op
+func (d *Database) WithTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	switch op {
+	case OpWrite:
+		timeout = d.timeouts.Write
+	case OpSearch:
+		timeout = d.timeouts.Search
+	default:
+		timeout = d.timeouts.Read
+	}
+
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// IsTimeout сообщает, что запрос прерван по таймауту (контекста или statement_timeout)
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	// Отмененный по контексту запрос lib/pq завершает ошибкой query_canceled
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// IsForeignKeyViolation сообщает, что запись ссылается на несуществующую строку (foreign_key_violation)
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// Replicas возвращает реплики для чтения
+func (d *Database) Replicas() []*Replica {
+	return d.replicas.Replicas()
+}
+
+// MarkWrite отмечает, что пользователь только что выполнил запись.
+// В течение окна согласованности его чтения не должны отставать от этой записи.
+func (d *Database) MarkWrite(ctx context.Context, userID int) {
+	var lsn uint64
+	if d.sessions.Mode() == ConsistencyLSN {
+		var err error
+		if lsn, err = currentWALLSN(ctx, d.WriteDB); err != nil {
+			logging.FromContext(ctx).Warn("Failed to get current WAL LSN", logging.Err(err))
+		}
+	}
+	d.sessions.Mark(userID, lsn)
+}
+
+// Reader возвращает пул соединений для чтения данных пользователем userID.
+// Если пользователь недавно выполнял запись, а выбранная реплика могла ее еще не получить,
+// или исправных реплик нет, возвращается пул мастера.
+// userID = 0 означает чтение вне сессии пользователя.
+func (d *Database) Reader(ctx context.Context, userID int) *DB {
+	return d.ReaderMaxStaleness(ctx, userID, 0)
+}
+
+// ReaderMaxStaleness аналогичен Reader, но пропускает реплики, отстающие больше чем
+// на maxStaleness (0 - допустимо любое отставание в пределах READ_DB_MAX_LAG)
+func (d *Database) ReaderMaxStaleness(ctx context.Context, userID int, maxStaleness time.Duration) *DB {
+	replica := d.replicas.Pick(maxStaleness)
+	if replica == nil {
+		monitoring.RecordReadRouting("primary", "no_suitable_replica")
+		return d.writer
+	}
+
+	mark, ok := d.sessions.Get(userID)
+	if !ok {
+		monitoring.RecordReadRouting(replica.Name, "default")
+		return d.replicaDB(replica)
+	}
+
+	if d.sessions.Mode() == ConsistencyLSN && mark.lsn != 0 {
+		replayed, inRecovery, err := replayedWALLSN(ctx, replica.DB)
+		switch {
+		case err != nil:
+			logging.FromContext(ctx).Warn("Failed to get WAL LSN of replica", "replica", replica.Name, logging.Err(err))
+		case !inRecovery || replayed >= mark.lsn:
+			monitoring.RecordReadRouting(replica.Name, "lsn_caught_up")
+			return d.replicaDB(replica)
+		}
+	}
+
+	monitoring.RecordReadRouting("primary", "recent_write")
+	return d.writer
+}
+
+// Writer возвращает пул мастера для записи
+func (d *Database) Writer() *DB {
+	return d.writer
+}
+
+func (d *Database) replicaDB(replica *Replica) *DB {
+	return newDB(replica.DB, replica.Name, d.slowQueryThreshold)
+}
+
+// buildDSN формирует строку подключения из отдельных параметров
+func buildDSN(host, port, dbname, user, password, sslMode, sslRootCert string) string {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		host, port, user, password, dbname, sslMode)
+	if sslRootCert != "" {
+		dsn += " sslrootcert=" + sslRootCert
+	}
+	return dsn
+}
 
-	db, err := sql.Open("postgres", connStr)
+// withSSLMode добавляет параметры TLS в DSN формата postgres://..., если они не указаны явно
+func withSSLMode(dsn, sslMode, sslRootCert string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return dsn
+	}
+
+	query := u.Query()
+	if query.Get("sslmode") == "" {
+		query.Set("sslmode", sslMode)
+	}
+	if query.Get("sslrootcert") == "" && sslRootCert != "" {
+		query.Set("sslrootcert", sslRootCert)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// openDB создает пул соединений с заданными настройками без проверки доступности
+func openDB(dsn string, pool config.DBPoolConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+func connectDB(dsn string, pool config.DBPoolConfig) (*sql.DB, error) {
+	db, err := openDB(dsn, pool)
 	if err != nil {
 		return nil, err
 	}
 
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	log.Printf("Connected to database at %s:%s", host, port)
 	return db, nil
 }
 
@@ -65,8 +279,8 @@ func (d *Database) Close() {
 	if d.WriteDB != nil {
 		d.WriteDB.Close()
 	}
-	if d.ReadDB != nil {
-		d.ReadDB.Close()
+	if d.replicas != nil {
+		d.replicas.Close()
 	}
 }
 
@@ -85,8 +299,14 @@ func InitTables(db *Database) error {
         interests TEXT,
         city VARCHAR(100),
         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        deleted_at TIMESTAMP
     );
+
+    -- Мягкое удаление добавлено позже. Удаленный пользователь скрыт из всех запросов
+    -- и удаляется окончательно по истечении срока хранения
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+    CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
     
     CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
     CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
@@ -121,14 +341,135 @@ func InitTables(db *Database) error {
         user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
         title VARCHAR(255) NOT NULL,
         content TEXT NOT NULL,
+        visibility VARCHAR(20) NOT NULL DEFAULT 'public',
         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        deleted_at TIMESTAMP,
+        version INTEGER NOT NULL DEFAULT 1,
+        attachment_count INTEGER NOT NULL DEFAULT 0
     );
+
+    -- Видимость добавлена позже: для созданных ранее таблиц посты остаются публичными
+    ALTER TABLE posts ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'public';
+
+    -- Мягкое удаление постов, как у пользователей
+    ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+    CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at) WHERE deleted_at IS NOT NULL;
+
+    -- Версия поста растет при каждом изменении; у созданных ранее постов правок нет
+    ALTER TABLE posts ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
     
     -- Индексы для постов
     CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts(user_id);
     CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at);
     CREATE INDEX IF NOT EXISTS idx_posts_user_created ON posts(user_id, created_at);
+
+    -- Предыдущие версии постов: при каждом изменении сохраняется заменяемая версия
+    CREATE TABLE IF NOT EXISTS post_revisions (
+        post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+        version INTEGER NOT NULL,
+        title VARCHAR(255) NOT NULL,
+        content TEXT NOT NULL,
+        visibility VARCHAR(20) NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        replaced_at TIMESTAMP NOT NULL,
+        PRIMARY KEY (post_id, version)
+    );
+
+    -- Реакции на посты: у пользователя не больше одной реакции на пост
+    CREATE TABLE IF NOT EXISTS post_reactions (
+        post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        reaction VARCHAR(20) NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (post_id, user_id)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_post_reactions_post_reaction ON post_reactions(post_id, reaction);
+    CREATE INDEX IF NOT EXISTS idx_post_reactions_user_id ON post_reactions(user_id);
+
+    -- Счетчики реакций. Пересчитываются по post_reactions периодически,
+    -- а не при каждой реакции, чтобы популярные посты не упирались в блокировку одной строки
+    CREATE TABLE IF NOT EXISTS post_reaction_counts (
+        post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+        reaction VARCHAR(20) NOT NULL,
+        count INTEGER NOT NULL,
+        PRIMARY KEY (post_id, reaction)
+    );
+
+    -- Комментарии к постам. Ответы (parent_id) допускаются только на комментарии верхнего уровня
+    CREATE TABLE IF NOT EXISTS comments (
+        id SERIAL PRIMARY KEY,
+        post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+        content TEXT NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_comments_post_parent_id ON comments(post_id, parent_id, id);
+    CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id, id);
+    CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments(user_id);
+
+    -- Вложения постов. Файлы лежат в хранилище вложений (MEDIA_STORE), в таблице - их ключи
+    CREATE TABLE IF NOT EXISTS post_attachments (
+        id SERIAL PRIMARY KEY,
+        post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        blob_key VARCHAR(255) NOT NULL,
+        thumbnail_key VARCHAR(255) NOT NULL,
+        content_type VARCHAR(50) NOT NULL,
+        size BIGINT NOT NULL,
+        width INTEGER NOT NULL,
+        height INTEGER NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_post_attachments_post_id ON post_attachments(post_id, id);
+
+    -- Число вложений поста. Лимит проверяется условным увеличением счетчика: обновление
+    -- блокирует строку поста, поэтому параллельные загрузки не превышают лимит.
+    -- Для созданных ранее таблиц счетчик заполняется один раз при добавлении столбца.
+    DO $$
+    BEGIN
+        IF NOT EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_name = 'posts' AND column_name = 'attachment_count'
+        ) THEN
+            ALTER TABLE posts ADD COLUMN attachment_count INTEGER NOT NULL DEFAULT 0;
+            UPDATE posts p SET attachment_count = (SELECT COUNT(*) FROM post_attachments a WHERE a.post_id = p.id);
+        END IF;
+    END $$;
+
+    -- Журнал аудита. Без внешних ключей: записи сохраняются после удаления пользователей и постов
+    CREATE TABLE IF NOT EXISTS audit_log (
+        id BIGSERIAL PRIMARY KEY,
+        actor_id INTEGER,
+        action VARCHAR(50) NOT NULL,
+        target_type VARCHAR(50),
+        target_id INTEGER,
+        ip VARCHAR(45),
+        user_agent TEXT,
+        details JSONB,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_actor_created ON audit_log(actor_id, created_at);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_action_created ON audit_log(action, created_at);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);
+
+    -- Журнал только дополняется: изменение записей запрещено, удаляются лишь устаревшие
+    CREATE OR REPLACE FUNCTION audit_log_forbid_update() RETURNS trigger AS $$
+    BEGIN
+        RAISE EXCEPTION 'audit_log is append-only';
+    END;
+    $$ LANGUAGE plpgsql;
+
+    DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+    CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+        FOR EACH ROW EXECUTE FUNCTION audit_log_forbid_update();
     `
 
 	_, err := db.WriteDB.Exec(query)
@@ -136,6 +477,6 @@ func InitTables(db *Database) error {
 		return fmt.Errorf("failed to create tables: %w", err)
 	}
 
-	log.Println("Database tables initialized successfully")
+	slog.Info("Database tables initialized")
 	return nil
 }
@@ -0,0 +1,200 @@
+package database
+
+import (
+	"api/internal/logging"
+	"api/internal/monitoring"
+	"api/internal/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DB пул соединений, запросы через который записывают метрики по именованной операции:
+// длительность, ошибки и количество строк. Запросы дольше порога пишутся в лог медленных запросов.
+// Для запросов в рамках трассы создаются дочерние спаны.
+type DB struct {
+	db            *sql.DB
+	target        string // primary или имя реплики
+	slowThreshold time.Duration
+}
+
+func newDB(db *sql.DB, target string, slowThreshold time.Duration) *DB {
+	return &DB{
+		db:            db,
+		target:        target,
+		slowThreshold: slowThreshold,
+	}
+}
+
+// Target возвращает имя пула: primary или имя реплики
+func (d *DB) Target() string {
+	return d.target
+}
+
+// QueryContext выполняет запрос, возвращающий строки.
+// Метрики записываются при закрытии Rows, чтобы учесть время чтения результата.
+func (d *DB) QueryContext(ctx context.Context, operation, query string, args ...interface{}) (*Rows, error) {
+	ctx, span := d.startSpan(ctx, operation, query)
+	start := time.Now()
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		d.observe(ctx, span, operation, query, start, 0, err)
+		return nil, err
+	}
+
+	return &Rows{Rows: rows, db: d, ctx: ctx, span: span, operation: operation, query: query, start: start}, nil
+}
+
+// QueryRowContext выполняет запрос, возвращающий не более одной строки.
+// Метрики записываются при вызове Scan.
+func (d *DB) QueryRowContext(ctx context.Context, operation, query string, args ...interface{}) *Row {
+	ctx, span := d.startSpan(ctx, operation, query)
+	// Запрос выполняется сразу в QueryRowContext, поэтому время засекается до вызова
+	start := time.Now()
+
+	return &Row{
+		row:       d.db.QueryRowContext(ctx, query, args...),
+		db:        d,
+		ctx:       ctx,
+		span:      span,
+		operation: operation,
+		query:     query,
+		start:     start,
+	}
+}
+
+// ExecContext выполняет запрос без результата; количеством строк считается RowsAffected
+func (d *DB) ExecContext(ctx context.Context, operation, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := d.startSpan(ctx, operation, query)
+	start := time.Now()
+
+	result, err := d.db.ExecContext(ctx, query, args...)
+
+	var rows int64
+	if err == nil {
+		// Не все драйверы поддерживают RowsAffected, в этом случае строки не учитываются
+		if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+			rows = n
+		}
+	}
+
+	d.observe(ctx, span, operation, query, start, rows, err)
+	return result, err
+}
+
+func (d *DB) startSpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return tracing.StartChild(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(compactQuery(query)),
+			attribute.String("db.pool", d.target),
+		),
+	)
+}
+
+func (d *DB) observe(ctx context.Context, span trace.Span, operation, query string, start time.Time, rows int64, err error) {
+	duration := time.Since(start)
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows", rows))
+	}
+	span.End()
+
+	monitoring.ObserveDatabaseQuery(operation, err == nil, duration)
+	if err != nil {
+		monitoring.RecordDatabaseQueryError(operation, d.target)
+	} else {
+		monitoring.ObserveDatabaseQueryRows(operation, rows)
+	}
+
+	if d.slowThreshold > 0 && duration >= d.slowThreshold {
+		monitoring.RecordSlowQuery(operation)
+		logging.FromContext(ctx).Warn("Slow query",
+			"operation", operation,
+			"target", d.target,
+			"duration_ms", duration.Milliseconds(),
+			"rows", rows,
+			logging.Err(err),
+			"query", compactQuery(query),
+		)
+	}
+}
+
+// Row результат QueryRowContext
+type Row struct {
+	row       *sql.Row
+	db        *DB
+	ctx       context.Context
+	span      trace.Span
+	operation string
+	query     string
+	start     time.Time
+}
+
+// Scan копирует значения строки в dest.
+// Отсутствие строки (sql.ErrNoRows) не считается ошибкой запроса.
+func (r *Row) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+
+	switch {
+	case err == nil:
+		r.db.observe(r.ctx, r.span, r.operation, r.query, r.start, 1, nil)
+	case errors.Is(err, sql.ErrNoRows):
+		r.db.observe(r.ctx, r.span, r.operation, r.query, r.start, 0, nil)
+	default:
+		r.db.observe(r.ctx, r.span, r.operation, r.query, r.start, 0, err)
+	}
+
+	return err
+}
+
+// Rows результат QueryContext, считающий прочитанные строки
+type Rows struct {
+	*sql.Rows
+
+	db        *DB
+	ctx       context.Context
+	span      trace.Span
+	operation string
+	query     string
+	start     time.Time
+	count     int64
+	closed    bool
+}
+
+// Next переходит к следующей строке
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	return false
+}
+
+// Close закрывает Rows и записывает метрики запроса
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.db.observe(r.ctx, r.span, r.operation, r.query, r.start, r.count, r.Rows.Err())
+	}
+	return err
+}
+
+// compactQuery сворачивает пробелы в тексте запроса для однострочного лога
+func compactQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
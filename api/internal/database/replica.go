@@ -0,0 +1,319 @@
+package database
+
+import (
+	"api/internal/monitoring"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Стратегии выбора реплики для чтения
+const (
+	BalancerRoundRobin   = "round_robin"
+	BalancerLeastLatency = "least_latency"
+)
+
+// replicaStatusQuery возвращает состояние репликации: режим восстановления, примененную
+// позицию WAL, применены ли все полученные изменения, время с последней примененной
+// транзакции в секундах и подключен ли процесс приема WAL к мастеру
+const replicaStatusQuery = `
+    SELECT pg_is_in_recovery(),
+           pg_last_wal_replay_lsn()::text,
+           COALESCE(pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn(), false),
+           COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0),
+           EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming')
+`
+
+// replicaStatus результат replicaStatusQuery
+type replicaStatus struct {
+	inRecovery  bool
+	replayed    uint64
+	replayedAll bool
+	replayAge   time.Duration
+	streaming   bool
+}
+
+// Replica реплика для чтения и результаты ее последней проверки
+type Replica struct {
+	Name string
+	DB   *sql.DB
+
+	healthy  atomic.Bool
+	probed   atomic.Bool
+	latency  atomic.Int64 // время ответа на проверку, нс
+	lag      atomic.Int64 // отставание от мастера по времени, нс
+	lagKnown atomic.Bool  // false, если lag - только нижняя оценка
+	lagBytes atomic.Int64 // отставание от мастера по WAL, байт
+	probedAt atomic.Int64 // время последней проверки, нс с начала эпохи
+}
+
+// Healthy сообщает, используется ли реплика для чтения
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// Latency возвращает время ответа на последнюю проверку
+func (r *Replica) Latency() time.Duration {
+	return time.Duration(r.latency.Load())
+}
+
+// Lag возвращает отставание реплики по времени по последней проверке
+func (r *Replica) Lag() time.Duration {
+	return time.Duration(r.lag.Load())
+}
+
+// LagKnown сообщает, известно ли отставание точно. Если позиция WAL мастера не получена
+// или реплика не принимает WAL, Lag возвращает только нижнюю оценку.
+func (r *Replica) LagKnown() bool {
+	return r.lagKnown.Load()
+}
+
+// Staleness возвращает верхнюю оценку текущего отставания: отставание по последней
+// проверке плюс время, прошедшее с нее. ok = false, если отставание неизвестно.
+func (r *Replica) Staleness() (staleness time.Duration, ok bool) {
+	if !r.LagKnown() {
+		return 0, false
+	}
+	return r.Lag() + time.Since(time.Unix(0, r.probedAt.Load())), true
+}
+
+// LagBytes возвращает отставание реплики по WAL (в байтах) по последней проверке
+func (r *Replica) LagBytes() int64 {
+	return r.lagBytes.Load()
+}
+
+// ReplicaSet набор реплик с балансировкой и периодической проверкой состояния
+type ReplicaSet struct {
+	replicas []*Replica
+	primary  *sql.DB // используется только для сравнения позиций WAL
+
+	balancer string
+	next     atomic.Uint64
+
+	probeInterval time.Duration
+	maxLag        time.Duration
+	stop          chan struct{}
+}
+
+func NewReplicaSet(primary *sql.DB, replicas []*Replica, balancer string, probeInterval, maxLag time.Duration) (*ReplicaSet, error) {
+	switch balancer {
+	case BalancerRoundRobin, BalancerLeastLatency:
+	default:
+		return nil, fmt.Errorf("unknown replica balancer: %s", balancer)
+	}
+
+	s := &ReplicaSet{
+		replicas:      replicas,
+		primary:       primary,
+		balancer:      balancer,
+		probeInterval: probeInterval,
+		maxLag:        maxLag,
+		stop:          make(chan struct{}),
+	}
+
+	// Первая проверка синхронно, чтобы сразу не отправлять запросы на недоступные реплики
+	s.probeAll()
+
+	if probeInterval > 0 {
+		go s.run()
+	}
+
+	return s, nil
+}
+
+// Replicas возвращает все реплики набора
+func (s *ReplicaSet) Replicas() []*Replica {
+	return s.replicas
+}
+
+// Pick выбирает исправную реплику, отставание которой не превышает maxStaleness
+// (0 - без ограничения). Если подходящих реплик нет, возвращает nil.
+// Отставание оценивается сверху с учетом времени, прошедшего с последней проверки,
+// поэтому ограничения меньше интервала проверки часто направляют чтение на мастер.
+func (s *ReplicaSet) Pick(maxStaleness time.Duration) *Replica {
+	healthy := make([]*Replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		if !r.Healthy() {
+			continue
+		}
+		if maxStaleness > 0 {
+			if staleness, ok := r.Staleness(); !ok || staleness > maxStaleness {
+				continue
+			}
+		}
+		healthy = append(healthy, r)
+	}
+
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if s.balancer == BalancerLeastLatency {
+		best := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.Latency() < best.Latency() {
+				best = r
+			}
+		}
+		return best
+	}
+
+	n := s.next.Add(1)
+	return healthy[int(n%uint64(len(healthy)))]
+}
+
+// Close останавливает проверки и закрывает соединения с репликами
+func (s *ReplicaSet) Close() {
+	close(s.stop)
+	for _, r := range s.replicas {
+		r.DB.Close()
+	}
+}
+
+func (s *ReplicaSet) run() {
+	ticker := time.NewTicker(s.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.probeAll()
+		}
+	}
+}
+
+func (s *ReplicaSet) probeAll() {
+	// Позиция WAL мастера для расчета отставания реплик в байтах
+	ctx, cancel := context.WithTimeout(context.Background(), s.probeTimeout())
+	primaryLSN, err := currentWALLSN(ctx, s.primary)
+	cancel()
+	if err != nil {
+		// Без позиции мастера отставание реплик неизвестно
+		slog.Warn("Failed to get current WAL LSN of write database", "error", err)
+		primaryLSN = 0
+	}
+
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func(r *Replica) {
+			defer wg.Done()
+			s.probe(r, primaryLSN)
+		}(r)
+	}
+	wg.Wait()
+}
+
+// probe проверяет доступность и отставание реплики.
+// Недоступные и слишком отстающие реплики исключаются из балансировки.
+// primaryLSN = 0 означает, что позиция WAL мастера неизвестна.
+func (s *ReplicaSet) probe(r *Replica, primaryLSN uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.probeTimeout())
+	defer cancel()
+
+	start := time.Now()
+	err := r.DB.PingContext(ctx)
+	latency := time.Since(start)
+
+	var status replicaStatus
+	if err == nil {
+		status, err = queryReplicaStatus(ctx, r.DB)
+	}
+	lag, lagKnown := replicaLag(status, primaryLSN)
+
+	var lagBytes int64
+	if err == nil && status.inRecovery && primaryLSN > status.replayed {
+		lagBytes = int64(primaryLSN - status.replayed)
+	}
+
+	// Без позиции мастера у реплики, не принимающей WAL, время с последней транзакции
+	// не отличает обрыв репликации от отсутствия записей, поэтому она не исключается
+	healthy := err == nil
+	if healthy && s.maxLag > 0 && lag > s.maxLag && (status.streaming || primaryLSN != 0) {
+		healthy = false
+		err = fmt.Errorf("replication lag %v exceeds %v", lag, s.maxLag)
+	}
+
+	r.latency.Store(int64(latency))
+	r.lag.Store(int64(lag))
+	r.lagKnown.Store(err == nil && lagKnown)
+	r.lagBytes.Store(lagBytes)
+	r.probedAt.Store(start.UnixNano())
+
+	// Переходы логируются начиная со второй проверки: о результате первой сообщает NewDatabase
+	was := r.healthy.Swap(healthy)
+	if r.probed.Swap(true) && was != healthy {
+		if healthy {
+			slog.Info("Read replica is healthy again", "replica", r.Name)
+		} else {
+			slog.Warn("Read replica is ejected", "replica", r.Name, "error", err)
+		}
+	}
+
+	monitoring.ObserveReplicaProbe(r.Name, healthy, latency)
+	monitoring.SetReplicaLag(r.Name, lag, lagBytes)
+}
+
+// queryReplicaStatus возвращает состояние репликации сервера
+func queryReplicaStatus(ctx context.Context, db *sql.DB) (replicaStatus, error) {
+	var status replicaStatus
+	var replayed sql.NullString
+	var replayAge float64
+	err := db.QueryRowContext(ctx, replicaStatusQuery).Scan(
+		&status.inRecovery, &replayed, &status.replayedAll, &replayAge, &status.streaming,
+	)
+	if err != nil {
+		return replicaStatus{}, err
+	}
+	if replayed.Valid {
+		if status.replayed, err = parseLSN(replayed.String); err != nil {
+			return replicaStatus{}, err
+		}
+	}
+	status.replayAge = time.Duration(replayAge * float64(time.Second))
+	return status, nil
+}
+
+// replicaLag оценивает отставание реплики по времени. ok = false, если отставание
+// известно только снизу: позиция WAL мастера не получена или реплика не принимает WAL
+// (при обрыве репликации полученные изменения применены и отставание выглядело бы нулевым).
+func replicaLag(status replicaStatus, primaryLSN uint64) (lag time.Duration, ok bool) {
+	switch {
+	case !status.inRecovery:
+		return 0, true
+	case primaryLSN != 0 && status.replayed >= primaryLSN:
+		// Реплика догнала мастер на момент проверки
+		return 0, true
+	case !status.streaming:
+		// Изменений с мастера нет, отставание растет со временем последней транзакции
+		return status.replayAge, false
+	case status.replayedAll:
+		// Реплика применила все полученное, остальное в пути
+		return 0, primaryLSN != 0
+	default:
+		return status.replayAge, primaryLSN != 0
+	}
+}
+
+// probeTimeout возвращает таймаут одной проверки
+func (s *ReplicaSet) probeTimeout() time.Duration {
+	if s.probeInterval <= 0 {
+		return 5 * time.Second
+	}
+	return s.probeInterval
+}
+
+// replicaName возвращает имя реплики для логов и метрик (host:port из DSN)
+func replicaName(dsn string, index int) string {
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return fmt.Sprintf("replica-%d", index)
+}
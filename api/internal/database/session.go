@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Режимы согласованности чтения после записи
+const (
+	// ConsistencyOff все чтения идут на реплику
+	ConsistencyOff = "off"
+	// ConsistencyPrimary после записи чтения пользователя идут на мастер в течение окна
+	ConsistencyPrimary = "primary"
+	// ConsistencyLSN после записи реплика используется, только если она догнала LSN записи
+	ConsistencyLSN = "lsn"
+)
+
+// writeMark отметка о последней записи пользователя
+type writeMark struct {
+	lsn   uint64
+	until time.Time
+}
+
+// SessionTracker хранит отметки о записях пользователей для обеспечения
+// согласованности "чтение своих записей"
+type SessionTracker struct {
+	mode   string
+	window time.Duration
+
+	mu        sync.Mutex
+	marks     map[int]writeMark
+	lastSweep time.Time
+}
+
+func NewSessionTracker(mode string, window time.Duration) (*SessionTracker, error) {
+	switch mode {
+	case ConsistencyOff, ConsistencyPrimary, ConsistencyLSN:
+	default:
+		return nil, fmt.Errorf("unknown read consistency mode: %s", mode)
+	}
+
+	return &SessionTracker{
+		mode:      mode,
+		window:    window,
+		marks:     make(map[int]writeMark),
+		lastSweep: time.Now(),
+	}, nil
+}
+
+// Mode возвращает режим согласованности
+func (t *SessionTracker) Mode() string {
+	return t.mode
+}
+
+// Mark отмечает запись пользователя (lsn используется только в режиме lsn)
+func (t *SessionTracker) Mark(userID int, lsn uint64) {
+	if t.mode == ConsistencyOff || userID == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.marks[userID] = writeMark{lsn: lsn, until: now.Add(t.window)}
+
+	// Периодически удаляем истекшие отметки
+	if now.Sub(t.lastSweep) > t.window {
+		for id, mark := range t.marks {
+			if now.After(mark.until) {
+				delete(t.marks, id)
+			}
+		}
+		t.lastSweep = now
+	}
+}
+
+// Get возвращает действующую отметку о записи пользователя
+func (t *SessionTracker) Get(userID int) (writeMark, bool) {
+	if t.mode == ConsistencyOff || userID == 0 {
+		return writeMark{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	mark, ok := t.marks[userID]
+	if !ok {
+		return writeMark{}, false
+	}
+	if time.Now().After(mark.until) {
+		delete(t.marks, userID)
+		return writeMark{}, false
+	}
+	return mark, true
+}
+
+// currentWALLSN возвращает текущую позицию WAL на мастере
+func currentWALLSN(ctx context.Context, db *sql.DB) (uint64, error) {
+	var lsn string
+	if err := db.QueryRowContext(ctx, `SELECT pg_current_wal_lsn()::text`).Scan(&lsn); err != nil {
+		return 0, err
+	}
+	return parseLSN(lsn)
+}
+
+// replayedWALLSN возвращает позицию WAL, примененную репликой.
+// Для сервера, не находящегося в режиме восстановления, ok = false.
+func replayedWALLSN(ctx context.Context, db *sql.DB) (lsn uint64, ok bool, err error) {
+	var value sql.NullString
+	if err := db.QueryRowContext(ctx, `SELECT pg_last_wal_replay_lsn()::text`).Scan(&value); err != nil {
+		return 0, false, err
+	}
+	if !value.Valid {
+		return 0, false, nil
+	}
+	lsn, err = parseLSN(value.String)
+	return lsn, err == nil, err
+}
+
+// parseLSN разбирает LSN формата "16/B374D848" в число
+func parseLSN(value string) (uint64, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid LSN: %s", value)
+	}
+
+	hi, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN: %s", value)
+	}
+	lo, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN: %s", value)
+	}
+
+	return hi<<32 | lo, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLSN(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint64
+		wantErr bool
+	}{
+		{value: "0/0", want: 0},
+		{value: "16/B374D848", want: 0x16<<32 | 0xB374D848},
+		{value: "0/3000148", want: 0x3000148},
+		{value: "FFFFFFFF/FFFFFFFF", want: 0xFFFFFFFFFFFFFFFF},
+		{value: "", wantErr: true},
+		{value: "16B374D848", wantErr: true},
+		{value: "16/", wantErr: true},
+		{value: "/B374D848", wantErr: true},
+		{value: "1/2/3", wantErr: true},
+		{value: "G/0", wantErr: true},
+		{value: "100000000/0", wantErr: true},
+		{value: "0/100000000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLSN(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLSN(%q) = %d, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseLSN(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	// Позиции сравниваются как числа: старшая часть важнее младшей
+	low, _ := parseLSN("0/FFFFFFFF")
+	high, _ := parseLSN("1/0")
+	if low >= high {
+		t.Errorf("parseLSN(0/FFFFFFFF) = %d is not less than parseLSN(1/0) = %d", low, high)
+	}
+}
+
+func TestNewSessionTrackerUnknownMode(t *testing.T) {
+	if _, err := NewSessionTracker("sticky", time.Second); err == nil {
+		t.Fatal("NewSessionTracker with unknown mode: want error")
+	}
+}
+
+func TestSessionTracker(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		window  time.Duration
+		userID  int
+		lsn     uint64
+		wantOK  bool
+		wantLSN uint64
+	}{
+		{name: "off ignores writes", mode: ConsistencyOff, window: time.Hour, userID: 1, lsn: 10},
+		{name: "anonymous writes are not tracked", mode: ConsistencyPrimary, window: time.Hour, userID: 0},
+		{name: "primary mode marks user", mode: ConsistencyPrimary, window: time.Hour, userID: 1, wantOK: true},
+		{name: "lsn mode keeps write position", mode: ConsistencyLSN, window: time.Hour, userID: 1, lsn: 42, wantOK: true, wantLSN: 42},
+		{name: "mark expires after window", mode: ConsistencyLSN, window: -time.Second, userID: 1, lsn: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, err := NewSessionTracker(tt.mode, tt.window)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tracker.Mark(tt.userID, tt.lsn)
+			mark, ok := tracker.Get(tt.userID)
+			if ok != tt.wantOK {
+				t.Fatalf("Get(%d) ok = %v, want %v", tt.userID, ok, tt.wantOK)
+			}
+			if ok && mark.lsn != tt.wantLSN {
+				t.Errorf("Get(%d) lsn = %d, want %d", tt.userID, mark.lsn, tt.wantLSN)
+			}
+
+			// Отметка одного пользователя не влияет на чтения другого
+			if _, ok := tracker.Get(tt.userID + 1); ok {
+				t.Errorf("Get(%d) ok = true for user without writes", tt.userID+1)
+			}
+		})
+	}
+}
+
+func TestSessionTrackerLatestWriteWins(t *testing.T) {
+	tracker, err := NewSessionTracker(ConsistencyLSN, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker.Mark(1, 10)
+	tracker.Mark(1, 20)
+	if mark, ok := tracker.Get(1); !ok || mark.lsn != 20 {
+		t.Fatalf("Get(1) = %d, %v, want 20, true", mark.lsn, ok)
+	}
+}
+
+func TestSessionTrackerSweepsExpiredMarks(t *testing.T) {
+	// При отрицательном окне отметки истекают сразу, а очистка выполняется при каждой записи
+	tracker, err := NewSessionTracker(ConsistencyPrimary, -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for userID := 1; userID <= 100; userID++ {
+		tracker.Mark(userID, 0)
+	}
+	if n := len(tracker.marks); n != 0 {
+		t.Fatalf("marks = %d after sweep, want 0", n)
+	}
+
+	// Истекшая отметка удаляется и при чтении
+	tracker.marks[1] = writeMark{until: time.Now().Add(-time.Minute)}
+	if _, ok := tracker.Get(1); ok {
+		t.Fatal("Get(1) ok = true for expired mark")
+	}
+	if _, ok := tracker.marks[1]; ok {
+		t.Fatal("expired mark is not deleted on Get")
+	}
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"api/internal/media"
+	"api/internal/service"
+	"api/internal/validation"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Запас к размеру файла на заголовки и границы multipart
+const multipartOverhead = 64 << 10
+
+type AttachmentHandler struct {
+	postService *service.PostService
+	maxSize     int64
+}
+
+func NewAttachmentHandler(postService *service.PostService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{
+		postService: postService,
+		maxSize:     maxSize,
+	}
+}
+
+// UploadAttachment godoc
+// @Summary Прикрепить изображение к посту
+// @Description Загружает изображение (JPEG, PNG, GIF) в поле file формы multipart и строит миниатюру; доступно только автору поста
+// @Tags Posts
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID поста"
+// @Param file formData file true "Изображение"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /post/{id}/attachments [post]
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	// Слишком большое тело не читается целиком; точный размер файла проверяет валидация
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondError(c, validation.Attachment(maxBytesErr.Limit, h.maxSize, ""))
+			return
+		}
+		respondInvalidRequest(c, "Invalid request: multipart field file is required")
+		return
+	}
+	if fileHeader.Size > h.maxSize {
+		respondError(c, validation.Attachment(fileHeader.Size, h.maxSize, ""))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	attachment, err := h.postService.AddAttachment(c.Request.Context(), postID, userID, data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DeleteAttachment godoc
+// @Summary Удалить вложение
+// @Description Удаляет изображение и его миниатюру; доступно только автору поста
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID вложения"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /attachment/delete/{id} [delete]
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid attachment ID")
+		return
+	}
+
+	if err := h.postService.DeleteAttachment(c.Request.Context(), attachmentID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// GetMedia godoc
+// @Summary Получить файл вложения
+// @Description Отдает изображение по подписанной ссылке из поля url вложения. Заголовок авторизации не нужен,
+// @Description чтобы изображения загружались браузером напрямую: ссылка выдается конкретному читателю и действует ограниченное время.
+// @Description Доступ к посту проверяется при каждом запросе.
+// @Tags Posts
+// @Produce image/jpeg,image/png,image/gif
+// @Param id path int true "ID вложения"
+// @Param viewer query int true "ID читателя"
+// @Param expires query int true "Срок действия ссылки (Unix time)"
+// @Param signature query string true "Подпись ссылки"
+// @Success 200 {file} file
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /media/{id} [get]
+func (h *AttachmentHandler) GetMedia(c *gin.Context) {
+	h.serveMedia(c, false)
+}
+
+// GetMediaThumbnail godoc
+// @Summary Получить миниатюру вложения
+// @Description Отдает миниатюру по подписанной ссылке из поля thumbnail_url вложения
+// @Tags Posts
+// @Produce image/jpeg,image/png,image/gif
+// @Param id path int true "ID вложения"
+// @Param viewer query int true "ID читателя"
+// @Param expires query int true "Срок действия ссылки (Unix time)"
+// @Param signature query string true "Подпись ссылки"
+// @Success 200 {file} file
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /media/{id}/thumbnail [get]
+func (h *AttachmentHandler) GetMediaThumbnail(c *gin.Context) {
+	h.serveMedia(c, true)
+}
+
+func (h *AttachmentHandler) serveMedia(c *gin.Context, thumbnail bool) {
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid attachment ID")
+		return
+	}
+	viewerID, err := strconv.Atoi(c.Query("viewer"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid viewer")
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		respondInvalidRequest(c, "Invalid expires")
+		return
+	}
+
+	body, contentType, expiresAt, err := h.postService.OpenAttachment(c.Request.Context(), media.Link{
+		AttachmentID: attachmentID,
+		Thumbnail:    thumbnail,
+		ViewerID:     viewerID,
+		Expires:      expires,
+		Signature:    c.Query("signature"),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer body.Close()
+
+	// Ссылка выдана одному читателю: общие кэши ее не хранят, браузер - пока она действует
+	maxAge := int(time.Until(expiresAt).Seconds())
+	c.DataFromReader(http.StatusOK, -1, contentType, body, map[string]string{
+		"Cache-Control":          fmt.Sprintf("private, max-age=%d", maxAge),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"api/internal/models"
+	"api/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetAuditLog godoc
+// @Summary Журнал аудита
+// @Description Возвращает записи журнала аудита по фильтрам, новые первыми. Доступно только администраторам
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "ID пользователя, выполнившего действие"
+// @Param action query string false "Действие (user.login, post.delete, ...)"
+// @Param target_type query string false "Тип объекта (user, post)"
+// @Param target_id query int false "ID объекта"
+// @Param from query string false "Начало периода (RFC 3339)"
+// @Param to query string false "Конец периода (RFC 3339)"
+// @Param page query int false "Номер страницы" default(1)
+// @Param page_size query int false "Размер страницы" default(20)
+// @Success 200 {object} models.AuditLogResponse
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /admin/audit [get]
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	filter := &models.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+	}
+
+	var err error
+	if filter.ActorID, err = queryInt(c, "actor_id"); err != nil {
+		respondInvalidRequest(c, "Invalid actor_id")
+		return
+	}
+	if filter.TargetID, err = queryInt(c, "target_id"); err != nil {
+		respondInvalidRequest(c, "Invalid target_id")
+		return
+	}
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		respondInvalidRequest(c, "Invalid from, expected RFC 3339")
+		return
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		respondInvalidRequest(c, "Invalid to, expected RFC 3339")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	response, err := h.auditService.GetEntries(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// queryInt разбирает необязательный числовой параметр запроса
+func queryInt(c *gin.Context, name string) (*int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &intValue, nil
+}
+
+// queryTime разбирает необязательный параметр запроса со временем в формате RFC 3339
+func queryTime(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	timeValue, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &timeValue, nil
+}
@@ -9,14 +9,19 @@ import (
 )
 
 type CacheHandler struct {
-	cacheService *service.CacheService
-	postService  *service.PostService
+	cacheService  *service.CacheService
+	postService   *service.PostService
+	warmupService *service.CacheWarmupService
+
+	warmupOnInvalidate bool
 }
 
-func NewCacheHandler(cacheService *service.CacheService, postService *service.PostService) *CacheHandler {
+func NewCacheHandler(cacheService *service.CacheService, postService *service.PostService, warmupService *service.CacheWarmupService, warmupOnInvalidate bool) *CacheHandler {
 	return &CacheHandler{
-		cacheService: cacheService,
-		postService:  postService,
+		cacheService:       cacheService,
+		postService:        postService,
+		warmupService:      warmupService,
+		warmupOnInvalidate: warmupOnInvalidate,
 	}
 }
 
@@ -28,33 +33,40 @@ func NewCacheHandler(cacheService *service.CacheService, postService *service.Po
 // @Security BearerAuth
 // @Param user_id query int false "ID пользователя (по умолчанию - текущий)"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /cache/invalidate [post]
 func (h *CacheHandler) InvalidateCache(c *gin.Context) {
-	userID, err := getUserIDFromContext(c)
+	actorID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	userID := actorID
 
 	// Если указан другой user_id (для админов)
 	if targetUserIDStr := c.Query("user_id"); targetUserIDStr != "" {
 		userID, err = strconv.Atoi(targetUserIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			respondInvalidRequest(c, "Invalid user ID")
 			return
 		}
 	}
 
-	if err := h.cacheService.RefreshCache(userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.cacheService.RefreshCache(c.Request.Context(), actorID, userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
+	// Прогреваем кэш заново, чтобы следующий запрос не шел в БД
+	if h.warmupOnInvalidate {
+		h.warmupService.StartWarmUpUsers(c.Request.Context(), service.WarmupTriggerInvalidate, userID)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cache invalidated successfully",
 		"user_id": userID,
+		"warmup":  h.warmupOnInvalidate,
 	})
 }
 
@@ -65,12 +77,12 @@ func (h *CacheHandler) InvalidateCache(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} map[string]interface{}
-// @Failure 500 {object} map[string]string
+// @Failure 500 {object} models.Problem
 // @Router /cache/stats [get]
 func (h *CacheHandler) GetCacheStats(c *gin.Context) {
 	stats, err := h.cacheService.GetCacheStats()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
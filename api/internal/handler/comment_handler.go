@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"api/internal/models"
+	"api/internal/service"
+	"api/internal/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentHandler struct {
+	commentService *service.CommentService
+}
+
+func NewCommentHandler(commentService *service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		commentService: commentService,
+	}
+}
+
+// CreateComment godoc
+// @Summary Добавить комментарий
+// @Description Добавляет комментарий к посту или ответ на комментарий верхнего уровня (parent_id)
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID поста"
+// @Param request body models.CreateCommentRequest true "Данные комментария"
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /post/{id}/comments [post]
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := validation.CreateComment(&req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(c.Request.Context(), postID, userID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetComments godoc
+// @Summary Получить комментарии
+// @Description Возвращает комментарии верхнего уровня поста или ответы на комментарий parent_id в порядке создания
+// @Tags Comments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID поста"
+// @Param parent_id query int false "ID комментария, ответы на который нужны"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor)"
+// @Param limit query int false "Размер страницы" default(20)
+// @Success 200 {object} models.CommentsPage
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /post/{id}/comments [get]
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	var parentID *int
+	if parentIDStr := c.Query("parent_id"); parentIDStr != "" {
+		id, err := strconv.Atoi(parentIDStr)
+		if err != nil {
+			respondInvalidRequest(c, "Invalid parent comment ID")
+			return
+		}
+		parentID = &id
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	page, err := h.commentService.GetComments(c.Request.Context(), userID, postID, parentID, c.Query("cursor"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// UpdateComment godoc
+// @Summary Изменить комментарий
+// @Description Изменяет текст комментария; доступно только автору комментария
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID комментария"
+// @Param request body models.UpdateCommentRequest true "Новый текст"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /comment/update/{id} [put]
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	commentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid comment ID")
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := validation.UpdateComment(&req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.commentService.UpdateComment(c.Request.Context(), commentID, userID, &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully"})
+}
+
+// DeleteComment godoc
+// @Summary Удалить комментарий
+// @Description Удаляет комментарий вместе с ответами; доступно автору комментария и автору поста
+// @Tags Comments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID комментария"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /comment/delete/{id} [delete]
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	commentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid comment ID")
+		return
+	}
+
+	if err := h.commentService.DeleteComment(c.Request.Context(), commentID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
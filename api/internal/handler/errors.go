@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"api/internal/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError передает ошибку в ErrorMiddleware, который выбирает код статуса
+// по виду ошибки и отвечает в формате problem+json
+func respondError(c *gin.Context, err error) {
+	_ = c.Error(err)
+}
+
+// respondInvalidRequest отвечает 400, если запрос не удалось разобрать (не разобрано тело, неверный параметр).
+// Ошибки в значениях полей возвращает слой валидации с кодом 422.
+func respondInvalidRequest(c *gin.Context, message string) {
+	respondError(c, apperror.BadRequest(apperror.CodeInvalidRequest, message))
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"api/internal/models"
 	"api/internal/service"
+	"api/internal/validation"
 	"net/http"
 	"strconv"
 
@@ -28,24 +29,29 @@ func NewFriendHandler(friendService *service.FriendService) *FriendHandler {
 // @Security BearerAuth
 // @Param request body models.FriendRequest true "Данные для добавления друга"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /friend/add [post]
 func (h *FriendHandler) AddFriend(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	var req models.FriendRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		respondInvalidRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := validation.Friend(&req); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if err := h.friendService.AddFriend(userID, req.FriendID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.friendService.AddFriend(c.Request.Context(), userID, req.FriendID); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -61,24 +67,29 @@ func (h *FriendHandler) AddFriend(c *gin.Context) {
 // @Security BearerAuth
 // @Param request body models.FriendRequest true "Данные для удаления друга"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /friend/delete [post]
 func (h *FriendHandler) DeleteFriend(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	var req models.FriendRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		respondInvalidRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := validation.Friend(&req); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if err := h.friendService.DeleteFriend(userID, req.FriendID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.friendService.DeleteFriend(c.Request.Context(), userID, req.FriendID); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -92,18 +103,18 @@ func (h *FriendHandler) DeleteFriend(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {array} models.FriendResponse
-// @Failure 500 {object} map[string]string
+// @Failure 500 {object} models.Problem
 // @Router /friends [get]
 func (h *FriendHandler) GetFriends(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	friends, err := h.friendService.GetFriends(userID)
+	friends, err := h.friendService.GetFriends(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -118,26 +129,26 @@ func (h *FriendHandler) GetFriends(c *gin.Context) {
 // @Security BearerAuth
 // @Param friend_id query int true "ID пользователя"
 // @Success 200 {object} models.FriendshipStatus
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /friend/status [get]
 func (h *FriendHandler) GetFriendshipStatus(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	friendIDStr := c.Query("friend_id")
 	friendID, err := strconv.Atoi(friendIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid friend ID"})
+		respondInvalidRequest(c, "Invalid friend ID")
 		return
 	}
 
-	status, err := h.friendService.GetFriendshipStatus(userID, friendID)
+	status, err := h.friendService.GetFriendshipStatus(c.Request.Context(), userID, friendID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"api/internal/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Live godoc
+// @Summary Проба живости
+// @Description Отвечает 200, пока процесс обрабатывает запросы. Зависимости не проверяются,
+// @Description чтобы недоступность БД не приводила к перезапуску сервиса
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /health/live [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Ready godoc
+// @Summary Проба готовности
+// @Description Проверяет зависимости (БД для записи, реплики, отставание реплик, Redis) и возвращает
+// @Description результат и время каждой проверки. 503 - отказала критичная проверка или сервис останавливается
+// @Tags Health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /health/ready [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown || report.Status == health.StatusShuttingDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
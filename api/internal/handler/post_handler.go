@@ -3,8 +3,12 @@ package handler
 import (
 	"api/internal/models"
 	"api/internal/service"
+	"api/internal/validation"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,25 +32,30 @@ func NewPostHandler(postService *service.PostService) *PostHandler {
 // @Security BearerAuth
 // @Param request body models.CreatePostRequest true "Данные поста"
 // @Success 201 {object} models.Post
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /post/create [post]
 func (h *PostHandler) CreatePost(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	var req models.CreatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		respondInvalidRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := validation.CreatePost(&req); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	post, err := h.postService.CreatePost(userID, &req)
+	post, err := h.postService.CreatePost(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -55,111 +64,289 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 // GetPost godoc
 // @Summary Получить пост
-// @Description Возвращает пост по ID
+// @Description Возвращает пост по ID, если он виден текущему пользователю (скрытый пост - 404)
 // @Tags Posts
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID поста"
 // @Success 200 {object} models.PostResponse
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /post/get/{id} [get]
 func (h *PostHandler) GetPost(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
 	postIDStr := c.Param("id")
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		respondInvalidRequest(c, "Invalid post ID")
 		return
 	}
 
-	post, err := h.postService.GetPost(postID)
+	post, err := h.postService.GetPost(c.Request.Context(), postID, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
+	c.Header("ETag", postETag(post.Version))
 	c.JSON(http.StatusOK, post)
 }
 
 // UpdatePost godoc
 // @Summary Обновить пост
-// @Description Обновляет существующий пост
+// @Description Обновляет существующий пост, предыдущая версия сохраняется в истории правок.
+// @Description С заголовком If-Match (ETag из ответа GET /post/get/{id}) пост обновляется, только если
+// @Description его не изменили после чтения, иначе возвращается 412. Новый ETag возвращается в заголовке ответа.
 // @Tags Posts
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID поста"
+// @Param If-Match header string false "ETag прочитанной версии поста; допускаются слабые ETag и список"
 // @Param request body models.UpdatePostRequest true "Данные для обновления"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /post/update/{id} [put]
 func (h *PostHandler) UpdatePost(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	postIDStr := c.Param("id")
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	expectedVersions, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid If-Match header: expected post ETag")
 		return
 	}
 
 	var req models.UpdatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		respondInvalidRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := validation.UpdatePost(&req); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if err := h.postService.UpdatePost(postID, userID, &req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	version, err := h.postService.UpdatePost(c.Request.Context(), postID, userID, expectedVersions, &req)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
+	c.Header("ETag", postETag(version))
 	c.JSON(http.StatusOK, gin.H{"message": "Post updated successfully"})
 }
 
+// GetPostRevisions godoc
+// @Summary История правок поста
+// @Description Возвращает предыдущие версии поста, начиная с последней; доступно только автору
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID поста"
+// @Success 200 {array} models.PostRevision
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /post/{id}/revisions [get]
+func (h *PostHandler) GetPostRevisions(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	revisions, err := h.postService.GetPostRevisions(c.Request.Context(), postID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
 // DeletePost godoc
 // @Summary Удалить пост
-// @Description Удаляет пост пользователя
+// @Description Помечает пост пользователя удаленным. Пост можно восстановить в течение окна восстановления,
+// @Description по истечении срока хранения он удаляется окончательно вместе с вложениями.
 // @Tags Posts
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID поста"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /post/delete/{id} [delete]
 func (h *PostHandler) DeletePost(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	postIDStr := c.Param("id")
 	postID, err := strconv.Atoi(postIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		respondInvalidRequest(c, "Invalid post ID")
 		return
 	}
 
-	if err := h.postService.DeletePost(postID, userID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.postService.DeletePost(c.Request.Context(), postID, userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
 
+// RestorePost godoc
+// @Summary Восстановить пост
+// @Description Восстанавливает удаленный пост пользователя, если не истекло окно восстановления
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID поста"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /post/restore/{id} [post]
+func (h *PostHandler) RestorePost(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	if err := h.postService.RestorePost(c.Request.Context(), postID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post restored successfully"})
+}
+
+// LikePost godoc
+// @Summary Поставить реакцию на пост
+// @Description Ставит лайк или другую реакцию текущего пользователя; повторный вызов меняет реакцию. Без тела запроса ставится лайк.
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID поста"
+// @Param request body models.ReactionRequest false "Вид реакции (по умолчанию like)"
+// @Success 200 {object} models.PostReactions
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /post/{id}/like [post]
+func (h *PostHandler) LikePost(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	req := models.ReactionRequest{Reaction: models.ReactionLike}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondInvalidRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := validation.Reaction(&req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	reactions, err := h.postService.SetReaction(c.Request.Context(), postID, userID, req.Reaction)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reactions)
+}
+
+// UnlikePost godoc
+// @Summary Снять реакцию с поста
+// @Description Снимает реакцию текущего пользователя с поста; без реакции ничего не меняет
+// @Tags Posts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID поста"
+// @Success 200 {object} models.PostReactions
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /post/{id}/like [delete]
+func (h *PostHandler) UnlikePost(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "Invalid post ID")
+		return
+	}
+
+	reactions, err := h.postService.RemoveReaction(c.Request.Context(), postID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reactions)
+}
+
 // GetUserPosts godoc
 // @Summary Получить посты пользователя
-// @Description Возвращает посты указанного пользователя
+// @Description Возвращает видимые текущему пользователю посты указанного пользователя
 // @Tags Posts
 // @Produce json
 // @Security BearerAuth
@@ -167,13 +354,13 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 // @Param page query int false "Номер страницы" default(1)
 // @Param page_size query int false "Размер страницы" default(20)
 // @Success 200 {object} models.FeedResponse
-// @Failure 500 {object} map[string]string
+// @Failure 500 {object} models.Problem
 // @Router /posts [get]
 func (h *PostHandler) GetUserPosts(c *gin.Context) {
 	var targetUserID int
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -181,7 +368,7 @@ func (h *PostHandler) GetUserPosts(c *gin.Context) {
 	if userIDStr := c.Query("user_id"); userIDStr != "" {
 		targetUserID, err = strconv.Atoi(userIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			respondInvalidRequest(c, "Invalid user ID")
 			return
 		}
 	} else {
@@ -191,9 +378,9 @@ func (h *PostHandler) GetUserPosts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
-	feed, err := h.postService.GetUserPosts(targetUserID, page, pageSize)
+	feed, err := h.postService.GetUserPosts(c.Request.Context(), userID, targetUserID, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -202,30 +389,62 @@ func (h *PostHandler) GetUserPosts(c *gin.Context) {
 
 // GetFeed godoc
 // @Summary Лента постов друзей
-// @Description Возвращает ленту постов друзей пользователя
+// @Description Возвращает ленту публичных постов и постов для друзей от друзей пользователя
 // @Tags Posts
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Номер страницы" default(1)
 // @Param page_size query int false "Размер страницы" default(20)
 // @Success 200 {object} models.FeedResponse
-// @Failure 500 {object} map[string]string
+// @Failure 500 {object} models.Problem
 // @Router /post/feed [get]
 func (h *PostHandler) GetFeed(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
-	feed, err := h.postService.GetFriendsPosts(userID, page, pageSize)
+	feed, err := h.postService.GetFriendsPosts(c.Request.Context(), userID, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, feed)
 }
+
+// postETag ETag поста - его версия
+func postETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseIfMatch возвращает версии поста из заголовка If-Match; пустой список, если заголовка нет
+// или он равен "*". Слабые ETag (W/"3") принимаются как сильные: прокси, сжимающие ответы,
+// превращают сильные ETag в слабые, а версия поста от этого не меняется.
+func parseIfMatch(header string) ([]int, error) {
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "" {
+			continue
+		}
+		if tag == "*" {
+			return nil, nil
+		}
+
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			return nil, err
+		}
+		version, err := strconv.Atoi(unquoted)
+		if err != nil || version < 1 {
+			return nil, errors.New("invalid post version")
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
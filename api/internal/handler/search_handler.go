@@ -3,6 +3,7 @@ package handler
 import (
 	"api/internal/models"
 	"api/internal/service"
+	"api/internal/validation"
 	"net/http"
 	"strconv"
 
@@ -28,13 +29,18 @@ func NewSearchHandler(userService *service.UserService) *SearchHandler {
 // @Param page query int false "Номер страницы" default(1)
 // @Param page_size query int false "Размер страницы" default(20)
 // @Success 200 {object} models.UserSearchResponse
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /user/search [get]
 func (h *SearchHandler) SearchUsers(c *gin.Context) {
 	var searchReq models.UserSearchRequest
 	if err := c.ShouldBindQuery(&searchReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters: " + err.Error()})
+		respondInvalidRequest(c, "Invalid search parameters: "+err.Error())
+		return
+	}
+	if err := validation.UserSearch(&searchReq); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -43,9 +49,9 @@ func (h *SearchHandler) SearchUsers(c *gin.Context) {
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
 	// Используем поиск с пагинацией
-	result, err := h.userService.SearchUsersWithPaging(searchReq.FirstName, searchReq.LastName, page, pageSize)
+	result, err := h.userService.SearchUsersWithPaging(c.Request.Context(), searchReq.FirstName, searchReq.LastName, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -61,19 +67,24 @@ func (h *SearchHandler) SearchUsers(c *gin.Context) {
 // @Param first_name query string true "Часть имени для поиска" example("Конст")
 // @Param last_name query string true "Часть фамилии для поиска" example("Оси")
 // @Success 200 {array} models.UserResponse
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /user/search/simple [get]
 func (h *SearchHandler) SearchUsersSimple(c *gin.Context) {
 	var searchReq models.UserSearchRequest
 	if err := c.ShouldBindQuery(&searchReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters: " + err.Error()})
+		respondInvalidRequest(c, "Invalid search parameters: "+err.Error())
+		return
+	}
+	if err := validation.UserSearch(&searchReq); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	users, err := h.userService.SearchUsers(searchReq.FirstName, searchReq.LastName)
+	users, err := h.userService.SearchUsers(c.Request.Context(), searchReq.FirstName, searchReq.LastName)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
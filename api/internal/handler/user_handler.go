@@ -1,9 +1,10 @@
 package handler
 
 import (
+	"api/internal/apperror"
 	"api/internal/models"
 	"api/internal/service"
-	"errors"
+	"api/internal/validation"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -22,12 +23,16 @@ func NewUserHandler(userService *service.UserService) *UserHandler {
 func (h *UserHandler) Register(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+	if err := validation.User(&user); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if err := h.userService.Register(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.userService.Register(c.Request.Context(), &user); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -50,13 +55,50 @@ func (h *UserHandler) Register(c *gin.Context) {
 func (h *UserHandler) Login(c *gin.Context) {
 	var loginReq models.LoginRequest
 	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+	if err := validation.Login(&loginReq); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	authResponse, err := h.userService.Login(&loginReq)
+	authResponse, err := h.userService.Login(c.Request.Context(), &loginReq)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authResponse)
+}
+
+// RestoreAccount godoc
+// @Summary Восстановить аккаунт
+// @Description Восстанавливает удаленный аккаунт по учетным данным, если не истекло окно восстановления, и выполняет вход
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body models.LoginRequest true "Учетные данные"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Router /account/restore [post]
+func (h *UserHandler) RestoreAccount(c *gin.Context) {
+	var loginReq models.LoginRequest
+	if err := c.ShouldBindJSON(&loginReq); err != nil {
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+	if err := validation.Login(&loginReq); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	authResponse, err := h.userService.RestoreAccount(c.Request.Context(), &loginReq)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -67,13 +109,13 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		respondInvalidRequest(c, "Invalid user ID")
 		return
 	}
 
-	user, err := h.userService.GetUserByID(id)
+	user, err := h.userService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, err)
 		return
 	}
 
@@ -81,9 +123,9 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 }
 
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.userService.GetAllUsers()
+	users, err := h.userService.GetAllUsers(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		respondError(c, err)
 		return
 	}
 
@@ -93,7 +135,7 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 func getUserIDFromContext(c *gin.Context) (int, error) {
 	userIDValue, exists := c.Get("user_id")
 	if !exists {
-		return 0, errors.New("user not authenticated")
+		return 0, apperror.Unauthorized(apperror.CodeUnauthorized, "User not authenticated")
 	}
 
 	switch v := userIDValue.(type) {
@@ -104,43 +146,88 @@ func getUserIDFromContext(c *gin.Context) (int, error) {
 	case int64:
 		return int(v), nil
 	default:
-		return 0, fmt.Errorf("invalid user ID type: %T", v)
+		return 0, apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid user ID in token").Wrap(fmt.Errorf("unexpected user ID type: %T", v))
 	}
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
-// func (h *UserHandler) UpdateProfile(c *gin.Context) {
-// 	userID, err := getUserIDFromContext(c)
-// 	if err != nil {
-// 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-// 		return
-// 	}
-
-// 	var updateReq models.UpdateUserRequest
-// 	if err := c.ShouldBindJSON(&updateReq); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 		return
-// 	}
-
-// 	if err := h.userService.UpdateUser(userID, &updateReq); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
-// }
+// UpdateProfile godoc
+// @Summary Обновить профиль
+// @Description Обновляет переданные поля профиля текущего пользователя
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateUserRequest true "Изменяемые поля профиля"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Router /profile [put]
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var updateReq models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		respondInvalidRequest(c, err.Error())
+		return
+	}
+	if err := validation.UpdateUser(&updateReq); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.userService.UpdateUser(c.Request.Context(), userID, &updateReq); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
+}
+
+// DeleteProfile godoc
+// @Summary Удалить аккаунт
+// @Description Помечает аккаунт текущего пользователя удаленным: профиль и посты скрываются, токены перестают действовать.
+// @Description Аккаунт можно восстановить через /account/restore в течение окна восстановления,
+// @Description по истечении срока хранения он удаляется окончательно вместе со всеми данными.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /profile [delete]
+func (h *UserHandler) DeleteProfile(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.userService.DeleteAccount(c.Request.Context(), userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"api/internal/cache"
+	"api/internal/database"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Имена проверок, используемые в HEALTH_CRITICAL_CHECKS
+const (
+	CheckWriteDB    = "write_db"
+	CheckReadDB     = "read_db"
+	CheckReplicaLag = "replica_lag"
+	CheckRedis      = "redis"
+)
+
+var knownChecks = map[string]bool{
+	CheckWriteDB:    true,
+	CheckReadDB:     true,
+	CheckReplicaLag: true,
+	CheckRedis:      true,
+}
+
+// WriteDB проверяет доступность базы данных для записи
+func WriteDB(db *database.Database) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		stats := db.WriteDB.Stats()
+		details := map[string]interface{}{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+		}
+		return details, db.WriteDB.PingContext(ctx)
+	}
+}
+
+// ReadDB проверяет доступность реплик для чтения.
+// Проверка не проходит, только если недоступны все реплики: тогда чтение идет с мастера.
+func ReadDB(db *database.Database) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		replicas := db.Replicas()
+		if len(replicas) == 0 {
+			return nil, errors.New("no read replicas configured")
+		}
+
+		states := make([]map[string]interface{}, len(replicas))
+		errs := make([]error, len(replicas))
+
+		var wg sync.WaitGroup
+		for i, replica := range replicas {
+			wg.Add(1)
+			go func(i int, replica *database.Replica) {
+				defer wg.Done()
+
+				start := time.Now()
+				errs[i] = replica.DB.PingContext(ctx)
+				states[i] = map[string]interface{}{
+					"name":       replica.Name,
+					"connected":  errs[i] == nil,
+					"in_use":     replica.Healthy(),
+					"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+				}
+			}(i, replica)
+		}
+		wg.Wait()
+
+		details := map[string]interface{}{"replicas": states}
+		for _, err := range errs {
+			if err == nil {
+				return details, nil
+			}
+		}
+		return details, fmt.Errorf("no read replica is reachable: %w", errors.Join(errs...))
+	}
+}
+
+// ReplicaLag проверяет отставание реплик по результатам последней периодической проверки.
+// Проверка не проходит, если отставание хотя бы одной реплики превышает maxLag (0 - без ограничения).
+func ReplicaLag(db *database.Database, maxLag time.Duration) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		replicas := db.Replicas()
+		states := make([]map[string]interface{}, 0, len(replicas))
+
+		var lagging []string
+		for _, replica := range replicas {
+			states = append(states, map[string]interface{}{
+				"name":        replica.Name,
+				"lag_seconds": replica.Lag().Seconds(),
+				"lag_bytes":   replica.LagBytes(),
+				"lag_known":   replica.LagKnown(),
+			})
+			if maxLag > 0 && replica.Lag() > maxLag {
+				lagging = append(lagging, replica.Name)
+			}
+		}
+
+		details := map[string]interface{}{
+			"replicas":        states,
+			"max_lag_seconds": maxLag.Seconds(),
+		}
+		if len(lagging) > 0 {
+			return details, fmt.Errorf("replication lag exceeds %v: %v", maxLag, lagging)
+		}
+		return details, nil
+	}
+}
+
+// Redis проверяет доступность Redis и состояние предохранителя
+func Redis(redisCache *cache.RedisCache) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		details := map[string]interface{}{
+			"client_type": redisCache.GetClientType(),
+			"breaker":     redisCache.BreakerState(),
+		}
+		return details, redisCache.HealthCheck(ctx)
+	}
+}
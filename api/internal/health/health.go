@@ -0,0 +1,160 @@
+package health
+
+import (
+	"api/internal/config"
+	"api/internal/monitoring"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Общее состояние сервиса
+const (
+	// Все проверки прошли
+	StatusOK = "ok"
+	// Отказали только некритичные проверки, сервис продолжает принимать запросы
+	StatusDegraded = "degraded"
+	// Отказала критичная проверка
+	StatusDown = "down"
+	// Сервис останавливается и не должен получать новые запросы
+	StatusShuttingDown = "shutting_down"
+)
+
+// Результат отдельной проверки
+const (
+	CheckPassed = "ok"
+	CheckFailed = "failed"
+)
+
+// CheckFunc проверяет зависимость. Подробности (состояние реплик, пула и т.п.)
+// попадают в ответ и возвращаются даже при ошибке.
+type CheckFunc func(ctx context.Context) (map[string]interface{}, error)
+
+// CheckResult результат проверки зависимости
+type CheckResult struct {
+	Name      string                 `json:"name"`
+	Status    string                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMs float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report результат проверки готовности
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// Checker выполняет проверки зависимостей для пробы готовности.
+// Отказ критичной проверки снимает готовность, некритичной - только отмечает деградацию.
+type Checker struct {
+	checks   []check
+	critical map[string]bool
+	timeout  time.Duration
+
+	shuttingDown atomic.Bool
+}
+
+// NewChecker создает набор проверок. Неизвестное имя в HEALTH_CRITICAL_CHECKS - ошибка:
+// иначе опечатка молча делала бы проверку некритичной.
+func NewChecker(cfg config.HealthConfig) (*Checker, error) {
+	critical := make(map[string]bool, len(cfg.CriticalChecks))
+	for _, name := range cfg.CriticalChecks {
+		if !knownChecks[name] {
+			return nil, fmt.Errorf("unknown health check in HEALTH_CRITICAL_CHECKS: %q", name)
+		}
+		critical[name] = true
+	}
+
+	return &Checker{
+		critical: critical,
+		timeout:  cfg.CheckTimeout,
+	}, nil
+}
+
+// Register добавляет проверку; критичность задается настройкой HEALTH_CRITICAL_CHECKS
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{
+		name:     name,
+		critical: c.critical[name],
+		fn:       fn,
+	})
+}
+
+// SetShuttingDown снимает готовность в начале остановки сервиса
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// ShuttingDown сообщает, что сервис останавливается
+func (c *Checker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Ready выполняет все проверки параллельно, каждую со своим таймаутом
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.ShuttingDown() {
+		return Report{Status: StatusShuttingDown, Checks: []CheckResult{}}
+	}
+
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func(i int, ch check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	status := StatusOK
+	for _, result := range results {
+		if result.Status == CheckPassed {
+			continue
+		}
+		if result.Critical {
+			status = StatusDown
+			break
+		}
+		status = StatusDegraded
+	}
+
+	return Report{Status: status, Checks: results}
+}
+
+func (c *Checker) run(ctx context.Context, ch check) CheckResult {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	details, err := ch.fn(ctx)
+	latency := time.Since(start)
+
+	monitoring.ObserveHealthCheck(ch.name, err == nil, latency)
+
+	result := CheckResult{
+		Name:      ch.name,
+		Status:    CheckPassed,
+		Critical:  ch.critical,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = CheckFailed
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"api/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Init создает логгер по настройкам и делает его логгером по умолчанию.
+// Сообщения стандартного пакета log (в том числе из библиотек) также попадают в него.
+func Init(cfg config.LoggingConfig) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json", "":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	case "text":
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", cfg.Format)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+
+	return logger, nil
+}
+
+// ParseLevel разбирает уровень логирования: debug, info, warn или error
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("unknown log level: %s", value)
+	}
+	return level, nil
+}
+
+// WithContext сохраняет логгер в контексте
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext возвращает логгер запроса из контекста или логгер по умолчанию
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Err атрибут с ошибкой
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
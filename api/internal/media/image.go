@@ -0,0 +1,157 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Поддерживаемые типы изображений
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeGIF  = "image/gif"
+)
+
+// AllowedTypes типы изображений, которые принимаются как вложения
+var AllowedTypes = []string{TypeJPEG, TypePNG, TypeGIF}
+
+// MaxPixels ограничивает размер изображения в пикселях: небольшой файл может
+// распаковываться в изображение, которое не поместится в память
+const MaxPixels = 40_000_000
+
+const thumbnailJPEGQuality = 85
+
+var (
+	ErrInvalidImage  = errors.New("invalid image")
+	ErrImageTooLarge = errors.New("image too large")
+)
+
+// Image сведения о загруженном изображении и его миниатюра
+type Image struct {
+	Width         int
+	Height        int
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// DetectType определяет тип файла по содержимому, а не по имени или заголовку клиента
+func DetectType(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// Extension возвращает расширение файла для типа изображения
+func Extension(contentType string) string {
+	switch contentType {
+	case TypeJPEG:
+		return ".jpg"
+	case TypePNG:
+		return ".png"
+	case TypeGIF:
+		return ".gif"
+	default:
+		return ""
+	}
+}
+
+// Process декодирует изображение и строит миниатюру, вписанную в квадрат thumbnailSize.
+// Миниатюры фотографий сохраняются в JPEG, остальных изображений - в PNG, чтобы не терять прозрачность.
+func Process(data []byte, contentType string, thumbnailSize int) (*Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	var img image.Image
+	switch contentType {
+	case TypeJPEG:
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case TypePNG:
+		img, err = png.Decode(bytes.NewReader(data))
+	case TypeGIF:
+		// Для анимации миниатюра строится по первому кадру
+		img, err = gif.Decode(bytes.NewReader(data))
+	default:
+		return nil, ErrInvalidImage
+	}
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	thumb := Thumbnail(img, thumbnailSize)
+
+	var buf bytes.Buffer
+	result := &Image{Width: cfg.Width, Height: cfg.Height}
+	if contentType == TypeJPEG {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality})
+		result.ThumbnailType = TypeJPEG
+	} else {
+		err = png.Encode(&buf, thumb)
+		result.ThumbnailType = TypePNG
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Thumbnail = buf.Bytes()
+	return result, nil
+}
+
+// Thumbnail уменьшает изображение с сохранением пропорций так, чтобы большая сторона
+// не превышала maxSide. Каждый пиксель миниатюры - среднее по соответствующей области
+// исходного изображения. Изображения меньше maxSide не увеличиваются.
+func Thumbnail(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return src
+	}
+
+	thumbWidth, thumbHeight := maxSide, maxSide
+	if width > height {
+		thumbHeight = max(1, height*maxSide/width)
+	} else {
+		thumbWidth = max(1, width*maxSide/height)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for ty := 0; ty < thumbHeight; ty++ {
+		y0 := bounds.Min.Y + ty*height/thumbHeight
+		y1 := max(y0+1, bounds.Min.Y+(ty+1)*height/thumbHeight)
+
+		for tx := 0; tx < thumbWidth; tx++ {
+			x0 := bounds.Min.X + tx*width/thumbWidth
+			x1 := max(x0+1, bounds.Min.X+(tx+1)*width/thumbWidth)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					pr, pg, pb, pa := src.At(x, y).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			// RGBA() возвращает 16-битные значения с premultiplied alpha, как и image.RGBA
+			i := dst.PixOffset(tx, ty)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
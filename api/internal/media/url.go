@@ -0,0 +1,101 @@
+package media
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid media URL signature")
+	ErrURLExpired       = errors.New("media URL expired")
+)
+
+// Link параметры подписанной ссылки на файл вложения. Ссылка выдается конкретному
+// читателю: при открытии файла заново проверяется, что пост ему виден.
+type Link struct {
+	AttachmentID int
+	Thumbnail    bool
+	ViewerID     int
+	Expires      int64
+	Signature    string
+}
+
+// URLSigner выдает и проверяет подписанные ссылки на файлы вложений.
+// Ссылки нужны, чтобы браузер загружал изображения без заголовка авторизации.
+type URLSigner struct {
+	baseURL string
+	secret  []byte
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewURLSigner создает подписчик ссылок вида baseURL/{id} и baseURL/{id}/thumbnail
+func NewURLSigner(baseURL, secret string, ttl time.Duration) *URLSigner {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &URLSigner{
+		baseURL: baseURL,
+		secret:  []byte(secret),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// URL возвращает подписанную ссылку на файл или миниатюру вложения для читателя viewerID.
+// Срок действия округляется до границы интервала ttl, поэтому в течение интервала
+// ссылка не меняется и браузер берет изображение из своего кэша. Ссылка действует от ttl до 2*ttl.
+func (s *URLSigner) URL(attachmentID int, thumbnail bool, viewerID int) string {
+	ttl := int64(s.ttl / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+	expires := (s.now().Unix()/ttl + 2) * ttl
+
+	link := Link{AttachmentID: attachmentID, Thumbnail: thumbnail, ViewerID: viewerID, Expires: expires}
+	query := url.Values{}
+	query.Set("viewer", strconv.Itoa(viewerID))
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(link))
+
+	path := fmt.Sprintf("%s/%d", s.baseURL, attachmentID)
+	if thumbnail {
+		path += "/thumbnail"
+	}
+	return path + "?" + query.Encode()
+}
+
+// Verify проверяет подпись и срок действия ссылки и возвращает время, до которого она действует
+func (s *URLSigner) Verify(link Link) (time.Time, error) {
+	signature, err := hex.DecodeString(link.Signature)
+	if err != nil || !hmac.Equal(signature, s.mac(link)) {
+		return time.Time{}, ErrInvalidSignature
+	}
+
+	expiresAt := time.Unix(link.Expires, 0)
+	if !s.now().Before(expiresAt) {
+		return time.Time{}, ErrURLExpired
+	}
+	return expiresAt, nil
+}
+
+func (s *URLSigner) sign(link Link) string {
+	return hex.EncodeToString(s.mac(link))
+}
+
+func (s *URLSigner) mac(link Link) []byte {
+	variant := "original"
+	if link.Thumbnail {
+		variant = "thumbnail"
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%d:%s:%d:%d", link.AttachmentID, variant, link.ViewerID, link.Expires)
+	return mac.Sum(nil)
+}
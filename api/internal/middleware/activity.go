@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityMiddleware отмечает активность аутентифицированного пользователя.
+// Должен подключаться после AuthMiddleware.
+func ActivityMiddleware(activityService *service.ActivityService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := c.Get("user_id"); ok {
+			if id, ok := userID.(int); ok {
+				activityService.Touch(c.Request.Context(), id)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"api/internal/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware пропускает только пользователей из ADMIN_USER_IDS.
+// Должен стоять после AuthMiddleware.
+func AdminMiddleware(adminUserIDs []int) gin.HandlerFunc {
+	admins := make(map[int]bool, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if id, isInt := userID.(int); !ok || !isInt || !admins[id] {
+			_ = c.Error(apperror.Forbidden(apperror.CodeAdminRequired, "Admin access required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientInfoMiddleware сохраняет в контексте запроса адрес и User-Agent клиента
+// для записей журнала аудита, которые пишут сервисы
+func ClientInfoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := service.WithClientInfo(c.Request.Context(), c.ClientIP(), c.Request.UserAgent())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -1,9 +1,9 @@
 package middleware
 
 import (
+	"api/internal/apperror"
 	"api/internal/service"
 	"errors"
-	"net/http"
 	"strconv"
 	"strings"
 
@@ -14,21 +14,21 @@ func AuthMiddleware(userService *service.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			_ = c.Error(apperror.Unauthorized(apperror.CodeUnauthorized, "Authorization header required"))
 			c.Abort()
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
+			_ = c.Error(apperror.Unauthorized(apperror.CodeUnauthorized, "Bearer token required"))
 			c.Abort()
 			return
 		}
 
 		claims, err := userService.ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			_ = c.Error(apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid token").Wrap(err))
 			c.Abort()
 			return
 		}
@@ -36,7 +36,14 @@ func AuthMiddleware(userService *service.UserService) gin.HandlerFunc {
 		// Безопасное извлечение user_id
 		userID, err := extractUserID(claims)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+			_ = c.Error(apperror.Unauthorized(apperror.CodeInvalidToken, "Invalid user ID in token").Wrap(err))
+			c.Abort()
+			return
+		}
+
+		// Токены удаленного аккаунта перестают действовать сразу, а не по истечении срока
+		if err := userService.CheckActive(c.Request.Context(), userID); err != nil {
+			_ = c.Error(err)
 			c.Abort()
 			return
 		}
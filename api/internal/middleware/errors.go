@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"api/internal/apperror"
+	"api/internal/database"
+	"api/internal/models"
+	"api/internal/tracing"
+	"api/internal/validation"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType тип содержимого ответа с ошибкой (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest клиент закрыл соединение, не дождавшись ответа (нестандартный код nginx)
+const StatusClientClosedRequest = 499
+
+// ErrorMiddleware превращает ошибку, переданную обработчиком через c.Error,
+// в ответ application/problem+json с кодом статуса по виду ошибки.
+// Если обработчик уже записал ответ, ошибка остается только в логе.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		problem := newProblem(c, c.Errors.Last().Err)
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// RecoveryHandler отвечает 500 в формате problem+json на панику в обработчике
+func RecoveryHandler(c *gin.Context, recovered any) {
+	_ = c.Error(fmt.Errorf("panic: %v", recovered))
+	c.Abort()
+}
+
+// NoRouteHandler ошибка для запросов к несуществующим путям
+func NoRouteHandler(c *gin.Context) {
+	_ = c.Error(apperror.NotFound(apperror.CodeRouteNotFound, "Route not found"))
+}
+
+// newProblem сопоставляет ошибке код статуса и код ошибки.
+// Текст внутренних ошибок клиенту не отдается, он попадает в лог запроса.
+func newProblem(c *gin.Context, err error) models.Problem {
+	status, code, detail := http.StatusInternalServerError, apperror.CodeInternal, "Internal server error"
+
+	switch appErr, ok := apperror.As(err); {
+	case errors.Is(c.Request.Context().Err(), context.Canceled) || errors.Is(err, context.Canceled):
+		status, code, detail = StatusClientClosedRequest, apperror.CodeRequestCanceled, "Request canceled"
+	case database.IsTimeout(err):
+		status, code, detail = http.StatusGatewayTimeout, apperror.CodeTimeout, "Request timed out"
+	case ok:
+		status, code, detail = statusOf(appErr), appErr.Code, appErr.Message
+	}
+
+	problem := models.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		TraceID:  tracing.TraceID(c.Request.Context()),
+	}
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		locale := validation.NegotiateLocale(c.GetHeader("Accept-Language"))
+		problem.Detail = validation.Summary(locale)
+		problem.Errors = fieldErrs.Localize(locale)
+		c.Header("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+	}
+	if status == StatusClientClosedRequest {
+		problem.Title = "Client Closed Request"
+	}
+	if requestID, ok := c.Get(RequestIDKey); ok {
+		problem.RequestID, _ = requestID.(string)
+	}
+	return problem
+}
+
+// statusOf возвращает код статуса HTTP для вида доменной ошибки
+func statusOf(err *apperror.Error) int {
+	switch {
+	case errors.Is(err.Kind, apperror.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err.Kind, apperror.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err.Kind, apperror.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err.Kind, apperror.ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err.Kind, apperror.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err.Kind, apperror.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err.Kind, apperror.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"api/internal/logging"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDKey ключ ID запроса в контексте Gin
+const RequestIDKey = "request_id"
+
+// RequestIDHeader заголовок с ID запроса
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину принимаемого ID запроса
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware принимает ID запроса из заголовка X-Request-ID или генерирует новый,
+// возвращает его в ответе и сохраняет в контексте логгер с ID запроса и трассы.
+// Должен подключаться после TracingMiddleware.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := c.Request.Context()
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", requestID))
+
+		logger := slog.Default().With(slog.String("request_id", requestID))
+		if traceID := c.GetString(TraceIDKey); traceID != "" {
+			logger = logger.With(slog.String("trace_id", traceID))
+		}
+		c.Request = c.Request.WithContext(logging.WithContext(ctx, logger))
+
+		c.Next()
+	}
+}
+
+// validRequestID проверяет, что ID запроса клиента можно безопасно писать в логи и заголовки
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) || r == ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLogMiddleware пишет в лог запроса строку о каждом обработанном запросе.
+// Должен подключаться после RequestIDMiddleware.
+func AccessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Пропускаем метрики Prometheus
+		if c.Request.URL.Path == "/metrics" {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("response_bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).LogAttrs(ctx, level, "HTTP request", attrs...)
+	}
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"api/internal/monitoring"
-	"bytes"
 	"io"
 	"net/http"
 	"time"
@@ -10,7 +9,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// PrometheusMiddleware middleware для сбора метрик HTTP запросов
+// unmatchedRoute метка пути для запросов, не совпавших ни с одним маршрутом.
+// Фактический путь не используется, чтобы сканеры не создавали новые ряды метрик.
+const unmatchedRoute = "unmatched"
+
+// PrometheusMiddleware middleware для сбора метрик HTTP запросов.
+// Размеры запроса и ответа считаются по мере чтения и записи, без буферизации тела.
 func PrometheusMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Пропускаем метрики Prometheus
@@ -20,97 +24,67 @@ func PrometheusMiddleware() gin.HandlerFunc {
 		}
 
 		start := time.Now()
+		method := metricMethod(c.Request.Method)
 
-		// Захватываем размер запроса
-		var requestSize int64 = -1
-		if c.Request.ContentLength > 0 {
-			requestSize = c.Request.ContentLength
-		} else if c.Request.Body != nil && c.Request.Method != "GET" && c.Request.Method != "HEAD" {
-			// Сохраняем оригинальное тело
-			var bodyBytes []byte
-			if c.Request.Body != nil {
-				bodyBytes, _ = io.ReadAll(c.Request.Body)
-				requestSize = int64(len(bodyBytes))
-				// Восстанавливаем тело запроса
-				c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-			}
+		// Маршрут определяется до вызова middleware, поэтому шаблон уже известен
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
 		}
 
-		// Создаем кастомный writer для захвата ответа
-		writer := &responseWriter{
-			ResponseWriter: c.Writer,
-			body:           &bytes.Buffer{},
-			statusCode:     http.StatusOK, // Статус по умолчанию
+		done := monitoring.HTTPRequestStarted(method, route)
+		defer done()
+
+		// Без Content-Length (chunked) размер запроса считается по прочитанным байтам
+		var body *countingReader
+		if c.Request.ContentLength < 0 && c.Request.Body != nil {
+			body = &countingReader{ReadCloser: c.Request.Body}
+			c.Request.Body = body
 		}
-		c.Writer = writer
 
 		c.Next()
 
-		duration := time.Since(start)
-		status := writer.statusCode
-
-		// Получаем размер ответа
-		responseSize := int64(writer.body.Len())
-		if responseSize == 0 {
-			responseSize = -1
+		requestSize := c.Request.ContentLength
+		if body != nil {
+			requestSize = body.n
 		}
 
-		// Используем путь из контекста Gin (более надежно)
-		path := c.FullPath()
-		if path == "" {
-			path = c.Request.URL.Path
+		// Size возвращает -1, если тело ответа не записывалось
+		responseSize := int64(c.Writer.Size())
+		if responseSize < 0 {
+			responseSize = 0
 		}
 
-		// Записываем метрики
 		monitoring.ObserveHTTPRequest(
-			c.Request.Method,
-			path,
-			status,
-			duration,
+			method,
+			route,
+			c.Writer.Status(),
+			time.Since(start),
 			requestSize,
 			responseSize,
 		)
 	}
 }
 
-// responseWriter кастомный ResponseWriter для захвата ответа
-type responseWriter struct {
-	gin.ResponseWriter
-	body       *bytes.Buffer
-	statusCode int
-	size       int
-}
-
-// Write перехватывает запись тела ответа
-func (w *responseWriter) Write(b []byte) (int, error) {
-	if w.body != nil {
-		w.body.Write(b)
+// metricMethod возвращает метод для метки; нестандартные методы объединяются в OTHER
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
 	}
-	size, err := w.ResponseWriter.Write(b)
-	w.size += size
-	return size, err
 }
 
-// WriteHeader перехватывает установку статус кода
-func (w *responseWriter) WriteHeader(statusCode int) {
-	w.statusCode = statusCode
-	w.ResponseWriter.WriteHeader(statusCode)
+// countingReader считает байты, прочитанные из тела запроса
+type countingReader struct {
+	io.ReadCloser
+	n int64
 }
 
-// Status возвращает статус код (для совместимости с gin)
-func (w *responseWriter) Status() int {
-	return w.statusCode
-}
-
-// Size возвращает размер ответа
-func (w *responseWriter) Size() int {
-	return w.size
-}
-
-// Body возвращает тело ответа (для отладки)
-func (w *responseWriter) Body() string {
-	if w.body == nil {
-		return ""
-	}
-	return w.body.String()
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
 }
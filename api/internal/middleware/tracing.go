@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"api/internal/tracing"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDKey ключ ID трассы в контексте Gin
+const TraceIDKey = "trace_id"
+
+// TraceIDHeader заголовок ответа с ID трассы
+const TraceIDHeader = "X-Trace-ID"
+
+// TracingMiddleware создает серверный спан для каждого запроса, продолжая трассу
+// из заголовка traceparent, и возвращает ID трассы в заголовке X-Trace-ID
+func TracingMiddleware() gin.HandlerFunc {
+	tracer := tracing.Tracer()
+
+	return func(c *gin.Context) {
+		// Пропускаем метрики Prometheus
+		if c.Request.URL.Path == "/metrics" {
+			c.Next()
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Имя спана по шаблону маршрута, чтобы не плодить уникальные имена для каждого ID
+		route := c.FullPath()
+		spanName := c.Request.Method
+		if route != "" {
+			spanName = c.Request.Method + " " + route
+		}
+
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			c.Set(TraceIDKey, traceID)
+			c.Header(TraceIDHeader, traceID)
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if userID, ok := c.Get("user_id"); ok {
+			span.SetAttributes(semconv.EnduserID(fmt.Sprint(userID)))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
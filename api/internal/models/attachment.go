@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Attachment изображение, прикрепленное к посту. URL и ThumbnailURL - подписанные
+// ссылки для текущего читателя, они строятся при каждой выдаче.
+type Attachment struct {
+	ID           int       `json:"id"`
+	PostID       int       `json:"post_id"`
+	UserID       int       `json:"-"`
+	BlobKey      string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AuditEntry запись журнала аудита
+type AuditEntry struct {
+	ID         int64                  `json:"id"`
+	ActorID    *int                   `json:"actor_id"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type,omitempty"`
+	TargetID   *int                   `json:"target_id,omitempty"`
+	IP         string                 `json:"ip"`
+	UserAgent  string                 `json:"user_agent"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditFilter фильтры выборки журнала аудита (пустые поля не учитываются)
+type AuditFilter struct {
+	ActorID    *int
+	Action     string
+	TargetType string
+	TargetID   *int
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditLogResponse struct {
+	Entries  []AuditEntry `json:"entries"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Comment комментарий к посту; ParentID - комментарий верхнего уровня, если это ответ
+type Comment struct {
+	ID        int       `json:"id"`
+	PostID    int       `json:"post_id"`
+	UserID    int       `json:"user_id"`
+	ParentID  *int      `json:"parent_id,omitempty"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CommentResponse struct {
+	ID           int          `json:"id"`
+	PostID       int          `json:"post_id"`
+	UserID       int          `json:"user_id"`
+	ParentID     *int         `json:"parent_id,omitempty"`
+	User         UserResponse `json:"user"`
+	Content      string       `json:"content"`
+	RepliesCount int          `json:"replies_count"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type CreateCommentRequest struct {
+	Content  string `json:"content"`
+	ParentID *int   `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content"`
+}
+
+// CommentsPage страница комментариев; NextCursor пустой на последней странице
+type CommentsPage struct {
+	Comments   []CommentResponse `json:"comments"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
@@ -4,7 +4,7 @@ import "time"
 
 type FriendRequest struct {
 	// UserID   int `json:"user_id" binding:"required"`
-	FriendID int `json:"friend_id" binding:"required"`
+	FriendID int `json:"friend_id"`
 }
 
 type FriendResponse struct {
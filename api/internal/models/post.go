@@ -2,33 +2,79 @@ package models
 
 import "time"
 
+// PostVisibility кто видит пост кроме автора
+type PostVisibility string
+
+const (
+	VisibilityPublic  PostVisibility = "public"
+	VisibilityFriends PostVisibility = "friends"
+	VisibilityPrivate PostVisibility = "private"
+)
+
 type Post struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Title     string    `json:"title" binding:"required"`
-	Content   string    `json:"content" binding:"required"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         int            `json:"id"`
+	UserID     int            `json:"user_id"`
+	Title      string         `json:"title" binding:"required"`
+	Content    string         `json:"content" binding:"required"`
+	Visibility PostVisibility `json:"visibility"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Version    int            `json:"version"`
 }
 
 type PostResponse struct {
-	ID        int          `json:"id"`
-	UserID    int          `json:"user_id"`
-	User      UserResponse `json:"user"`
-	Title     string       `json:"title"`
-	Content   string       `json:"content"`
-	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
+	ID         int            `json:"id"`
+	UserID     int            `json:"user_id"`
+	User       UserResponse   `json:"user"`
+	Title      string         `json:"title"`
+	Content    string         `json:"content"`
+	Visibility PostVisibility `json:"visibility"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	// Версия растет при каждом изменении поста и передается в If-Match при обновлении
+	Version   int  `json:"version"`
+	Edited    bool `json:"edited"`
+	EditCount int  `json:"edit_count"`
+	// Количество комментариев вместе с ответами
+	CommentsCount int `json:"comments_count"`
+	// Вложения в порядке загрузки
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Реакции зависят от читателя и быстро меняются, поэтому заполняются при каждом чтении,
+	// в том числе поверх значений из кэша
+	PostReactions
+}
+
+// SetVersion заполняет версию и признаки правки: у нового поста версия 1,
+// каждое изменение увеличивает ее на единицу
+func (p *PostResponse) SetVersion(version int) {
+	p.Version = version
+	p.EditCount = version - 1
+	p.Edited = p.EditCount > 0
+}
+
+// PostRevision предыдущая версия поста, замененная правкой
+type PostRevision struct {
+	Version    int            `json:"version"`
+	Title      string         `json:"title"`
+	Content    string         `json:"content"`
+	Visibility PostVisibility `json:"visibility"`
+	// Когда версия была создана и когда ее заменила следующая
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
 }
 
+// CreatePostRequest без visibility создает публичный пост
 type CreatePostRequest struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content" binding:"required"`
+	Title      string         `json:"title"`
+	Content    string         `json:"content"`
+	Visibility PostVisibility `json:"visibility"`
 }
 
 type UpdatePostRequest struct {
-	Title   *string `json:"title"`
-	Content *string `json:"content"`
+	Title      *string         `json:"title"`
+	Content    *string         `json:"content"`
+	Visibility *PostVisibility `json:"visibility"`
 }
 
 type FeedResponse struct {
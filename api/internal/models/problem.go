@@ -0,0 +1,25 @@
+package models
+
+// Problem тело ответа с ошибкой в формате RFC 7807 (application/problem+json).
+// Code - стабильный код ошибки, на который может опираться клиент.
+// Errors заполняется для ошибок валидации (422).
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+
+	Errors []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem ошибка отдельного поля запроса.
+// Field пустой для ошибок запроса в целом (например, не передано ни одного поля).
+type FieldProblem struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// ReactionType вид реакции на пост
+type ReactionType string
+
+const (
+	ReactionLike  ReactionType = "like"
+	ReactionLove  ReactionType = "love"
+	ReactionHaha  ReactionType = "haha"
+	ReactionWow   ReactionType = "wow"
+	ReactionSad   ReactionType = "sad"
+	ReactionAngry ReactionType = "angry"
+)
+
+// ReactionTypes допустимые реакции
+var ReactionTypes = []ReactionType{ReactionLike, ReactionLove, ReactionHaha, ReactionWow, ReactionSad, ReactionAngry}
+
+// ReactionRequest тело запроса POST /post/:id/like; без тела ставится like
+type ReactionRequest struct {
+	Reaction ReactionType `json:"reaction"`
+}
+
+// PostReactions счетчики реакций поста и реакция текущего пользователя
+type PostReactions struct {
+	// Общее количество реакций всех видов
+	LikesCount int                  `json:"likes_count"`
+	Reactions  map[ReactionType]int `json:"reactions"`
+	LikedByMe  bool                 `json:"liked_by_me"`
+	MyReaction ReactionType         `json:"my_reaction,omitempty"`
+}
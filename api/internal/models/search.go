@@ -1,8 +1,8 @@
 package models
 
 type UserSearchRequest struct {
-	FirstName string `form:"first_name" binding:"required"`
-	LastName  string `form:"last_name" binding:"required"`
+	FirstName string `form:"first_name"`
+	LastName  string `form:"last_name"`
 }
 
 type UserSearchResponse struct {
@@ -0,0 +1,8 @@
+package models
+
+// Totals общее количество сущностей для бизнес-метрик
+type Totals struct {
+	Users       int
+	Posts       int
+	Friendships int
+}
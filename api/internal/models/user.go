@@ -14,17 +14,19 @@ const (
 
 type User struct {
 	ID        int       `json:"id"`
-	Username  string    `json:"username" binding:"required"`
-	Email     string    `json:"email" binding:"required,email"`
-	Password  string    `json:"password" binding:"required"`
-	FirstName string    `json:"first_name" binding:"required"`
-	LastName  string    `json:"last_name" binding:"required"`
-	BirthDate string    `json:"birth_date" binding:"required"` // Format: "2006-01-02"
-	Gender    Gender    `json:"gender" binding:"required,oneof=male female unknown"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Password  string    `json:"password"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	BirthDate string    `json:"birth_date"` // Format: "2006-01-02"
+	Gender    Gender    `json:"gender"`
 	Interests string    `json:"interests"`
 	City      string    `json:"city"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// Время мягкого удаления аккаунта, nil - аккаунт не удален
+	DeletedAt *time.Time `json:"-"`
 }
 
 type UserResponse struct {
@@ -41,8 +43,8 @@ type UserResponse struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
 }
 
 type AuthResponse struct {
@@ -58,3 +60,27 @@ type UpdateUserRequest struct {
 	Interests *string `json:"interests"`
 	City      *string `json:"city"`
 }
+
+// ChangedFields возвращает имена переданных в запросе полей
+func (r *UpdateUserRequest) ChangedFields() []string {
+	var fields []string
+	if r.FirstName != nil {
+		fields = append(fields, "first_name")
+	}
+	if r.LastName != nil {
+		fields = append(fields, "last_name")
+	}
+	if r.BirthDate != nil {
+		fields = append(fields, "birth_date")
+	}
+	if r.Gender != nil {
+		fields = append(fields, "gender")
+	}
+	if r.Interests != nil {
+		fields = append(fields, "interests")
+	}
+	if r.City != nil {
+		fields = append(fields, "city")
+	}
+	return fields
+}
@@ -0,0 +1,37 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	AuditRecordsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "audit_records_total",
+			Help: "Total number of audit log records by action and result",
+		},
+		[]string{"action", "result"},
+	)
+
+	AuditPrunedRecordsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "audit_pruned_records_total",
+			Help: "Total number of audit log records deleted after the retention period",
+		},
+	)
+)
+
+// RecordAuditWrite записывает результат записи в журнал аудита
+func RecordAuditWrite(action string, success bool) {
+	result := "written"
+	if !success {
+		result = "failed"
+	}
+	AuditRecordsTotal.WithLabelValues(action, result).Inc()
+}
+
+// RecordAuditPruned увеличивает счетчик удаленных устаревших записей аудита
+func RecordAuditPruned(count int64) {
+	AuditPrunedRecordsTotal.Add(float64(count))
+}
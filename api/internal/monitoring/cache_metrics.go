@@ -1,6 +1,8 @@
 package monitoring
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -37,6 +39,64 @@ var (
 		},
 		[]string{"type"},
 	)
+
+	CacheStoredBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_stored_bytes_total",
+			Help: "Total number of bytes written to cache",
+		},
+		[]string{"type", "codec"},
+	)
+
+	CacheEntrySize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cache_entry_size_bytes",
+			Help:    "Size of serialized cache entries in bytes",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		[]string{"type", "codec"},
+	)
+
+	RedisBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "redis_circuit_breaker_state",
+			Help: "Current state of Redis circuit breaker (1 for the active state)",
+		},
+		[]string{"state"},
+	)
+
+	RedisBreakerTransitionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "redis_circuit_breaker_transitions_total",
+			Help: "Total number of Redis circuit breaker state transitions",
+		},
+		[]string{"state"},
+	)
+
+	CacheWarmupRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_warmup_runs_total",
+			Help: "Total number of cache warm-up runs",
+		},
+		[]string{"trigger"},
+	)
+
+	CacheWarmupDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cache_warmup_duration_seconds",
+			Help:    "Duration of cache warm-up runs",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		},
+		[]string{"trigger"},
+	)
+
+	CacheWarmupEntriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_warmup_entries_total",
+			Help: "Total number of cache entries processed by warm-up",
+		},
+		[]string{"type", "status"},
+	)
 )
 
 // RecordCacheHit записывает попадание в кэш
@@ -53,3 +113,41 @@ func RecordCacheMiss(cacheType string) {
 func RecordCacheInvalidation(cacheType string) {
 	CacheInvalidationsTotal.WithLabelValues(cacheType).Inc()
 }
+
+// RecordCacheStoredBytes записывает размер сохраненной в кэш записи
+func RecordCacheStoredBytes(cacheType, codec string, size int) {
+	CacheStoredBytesTotal.WithLabelValues(cacheType, codec).Add(float64(size))
+	CacheEntrySize.WithLabelValues(cacheType, codec).Observe(float64(size))
+}
+
+// ObserveCacheWarmup записывает метрики завершенного прогрева кэша
+func ObserveCacheWarmup(trigger string, duration time.Duration) {
+	CacheWarmupRunsTotal.WithLabelValues(trigger).Inc()
+	CacheWarmupDuration.WithLabelValues(trigger).Observe(duration.Seconds())
+}
+
+// RecordCacheWarmupEntry записывает результат прогрева одной записи кэша
+func RecordCacheWarmupEntry(cacheType string, success bool) {
+	status := "failed"
+	if success {
+		status = "success"
+	}
+	CacheWarmupEntriesTotal.WithLabelValues(cacheType, status).Inc()
+}
+
+// SetRedisBreakerState отмечает текущее состояние предохранителя Redis
+func SetRedisBreakerState(state string) {
+	for _, s := range []string{"closed", "half_open", "open"} {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		RedisBreakerState.WithLabelValues(s).Set(value)
+	}
+}
+
+// RecordRedisBreakerTransition записывает переход предохранителя Redis в новое состояние
+func RecordRedisBreakerTransition(state string) {
+	RedisBreakerTransitionsTotal.WithLabelValues(state).Inc()
+	SetRedisBreakerState(state)
+}
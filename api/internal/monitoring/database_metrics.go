@@ -0,0 +1,122 @@
+package monitoring
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	DatabaseReadRoutingTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "database_read_routing_total",
+			Help: "Total number of read queries routed to primary or replica",
+		},
+		[]string{"target", "reason"},
+	)
+
+	DatabaseQueryErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "database_query_errors_total",
+			Help: "Total number of failed database queries",
+		},
+		[]string{"operation", "target"},
+	)
+
+	DatabaseQueryRows = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "database_query_rows",
+			Help:    "Number of rows returned or affected by database queries",
+			Buckets: []float64{0, 1, 5, 10, 20, 50, 100, 500, 1000},
+		},
+		[]string{"operation"},
+	)
+
+	DatabaseSlowQueriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "database_slow_queries_total",
+			Help: "Total number of database queries slower than the slow query threshold",
+		},
+		[]string{"operation"},
+	)
+
+	DatabaseReplicaUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "database_replica_up",
+			Help: "Whether the read replica is healthy and used for reads (1) or ejected (0)",
+		},
+		[]string{"replica"},
+	)
+
+	DatabaseReplicaProbeDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "database_replica_probe_duration_seconds",
+			Help:    "Duration of read replica health probes",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1},
+		},
+		[]string{"replica"},
+	)
+
+	DatabaseReplicaLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "database_replica_lag_seconds",
+			Help: "Replication lag of the read replica in seconds",
+		},
+		[]string{"replica"},
+	)
+
+	DatabaseReplicaLagBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "database_replica_lag_bytes",
+			Help: "Replication lag of the read replica in bytes of WAL",
+		},
+		[]string{"replica"},
+	)
+)
+
+// RecordDatabaseQueryError увеличивает счетчик ошибок запросов к базе данных
+func RecordDatabaseQueryError(operation, target string) {
+	DatabaseQueryErrorsTotal.WithLabelValues(operation, target).Inc()
+}
+
+// ObserveDatabaseQueryRows записывает количество строк, прочитанных или измененных запросом
+func ObserveDatabaseQueryRows(operation string, rows int64) {
+	DatabaseQueryRows.WithLabelValues(operation).Observe(float64(rows))
+}
+
+// RecordSlowQuery увеличивает счетчик медленных запросов
+func RecordSlowQuery(operation string) {
+	DatabaseSlowQueriesTotal.WithLabelValues(operation).Inc()
+}
+
+// ObserveReplicaProbe записывает результат проверки реплики
+func ObserveReplicaProbe(replica string, healthy bool, duration time.Duration) {
+	up := 0.0
+	if healthy {
+		up = 1
+	}
+	DatabaseReplicaUp.WithLabelValues(replica).Set(up)
+	DatabaseReplicaProbeDuration.WithLabelValues(replica).Observe(duration.Seconds())
+}
+
+// RegisterDBStats регистрирует метрики пула соединений (sql.DBStats) с меткой db_name
+func RegisterDBStats(name string, db *sql.DB) {
+	if err := prometheus.Register(collectors.NewDBStatsCollector(db, name)); err != nil {
+		slog.Warn("Failed to register DB stats collector", "db", name, "error", err)
+	}
+}
+
+// SetReplicaLag записывает отставание реплики
+func SetReplicaLag(replica string, lag time.Duration, lagBytes int64) {
+	DatabaseReplicaLag.WithLabelValues(replica).Set(lag.Seconds())
+	DatabaseReplicaLagBytes.WithLabelValues(replica).Set(float64(lagBytes))
+}
+
+// RecordReadRouting записывает выбор пула соединений для чтения (реплика или primary)
+func RecordReadRouting(target, reason string) {
+	DatabaseReadRoutingTotal.WithLabelValues(target, reason).Inc()
+}
@@ -0,0 +1,37 @@
+package monitoring
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	HealthCheckUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "health_check_up",
+			Help: "Result of the last readiness check of the dependency (1 - passed, 0 - failed)",
+		},
+		[]string{"check"},
+	)
+
+	HealthCheckDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "health_check_duration_seconds",
+			Help:    "Duration of readiness checks of dependencies",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 2},
+		},
+		[]string{"check"},
+	)
+)
+
+// ObserveHealthCheck записывает результат и длительность проверки зависимости
+func ObserveHealthCheck(check string, passed bool, duration time.Duration) {
+	up := 0.0
+	if passed {
+		up = 1
+	}
+	HealthCheckUp.WithLabelValues(check).Set(up)
+	HealthCheckDuration.WithLabelValues(check).Observe(duration.Seconds())
+}
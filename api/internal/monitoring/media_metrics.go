@@ -0,0 +1,46 @@
+package monitoring
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	MediaUploadsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "media_uploads_total",
+			Help: "Total number of attachment uploads by result (success, rejected, failed)",
+		},
+		[]string{"result"},
+	)
+
+	MediaUploadedBytes = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "media_uploaded_bytes_total",
+			Help: "Total size of stored attachments in bytes, without thumbnails",
+		},
+	)
+
+	MediaProcessingDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "media_processing_duration_seconds",
+			Help:    "Duration of image decoding and thumbnail generation",
+			Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+	)
+)
+
+// RecordMediaUpload записывает результат загрузки вложения
+func RecordMediaUpload(result string, size int64) {
+	MediaUploadsTotal.WithLabelValues(result).Inc()
+	if result == "success" {
+		MediaUploadedBytes.Add(float64(size))
+	}
+}
+
+// RecordMediaProcessing записывает длительность обработки изображения
+func RecordMediaProcessing(duration time.Duration) {
+	MediaProcessingDuration.Observe(duration.Seconds())
+}
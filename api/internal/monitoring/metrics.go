@@ -9,16 +9,29 @@ import (
 )
 
 var (
-	// HTTP метрики
+	// HTTP метрики. Метка path - шаблон маршрута (/post/get/:id), а не фактический путь.
+	// Гистограмма длительности экспортируется и с классическими бакетами, рассчитанными
+	// на ответы быстрее 100 мс, и как нативная гистограмма с экспоненциальными бакетами.
 	HttpRequestDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Duration of HTTP requests",
-			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10},
+			Name:                            "http_request_duration_seconds",
+			Help:                            "Duration of HTTP requests",
+			Buckets:                         []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 1, 2.5, 5},
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: time.Hour,
 		},
 		[]string{"method", "path", "status"},
 	)
 
+	HttpRequestsInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served",
+		},
+		[]string{"method", "path"},
+	)
+
 	HttpRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
@@ -67,24 +80,16 @@ var (
 		},
 		[]string{"operation", "success"},
 	)
-
-	// Системные метрики
-	GoroutinesCount = promauto.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "goroutines_count",
-			Help: "Current number of goroutines",
-		},
-	)
-
-	MemoryUsage = promauto.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "memory_usage_bytes",
-			Help: "Memory usage in bytes",
-		},
-		[]string{"type"},
-	)
 )
 
+// HTTPRequestStarted увеличивает количество обрабатываемых запросов;
+// возвращает функцию, которую нужно вызвать по завершении запроса
+func HTTPRequestStarted(method, path string) func() {
+	gauge := HttpRequestsInFlight.WithLabelValues(method, path)
+	gauge.Inc()
+	return gauge.Dec
+}
+
 // ObserveHTTPRequest записывает метрики HTTP запроса
 func ObserveHTTPRequest(method, path string, status int, duration time.Duration, requestSize, responseSize int64) {
 	statusStr := strconv.Itoa(status)
@@ -0,0 +1,19 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var PurgedRecordsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "soft_deleted_purged_total",
+		Help: "Total number of soft-deleted records permanently deleted after the retention period by kind (post, user)",
+	},
+	[]string{"kind"},
+)
+
+// RecordPurged увеличивает счетчик окончательно удаленных постов или пользователей
+func RecordPurged(kind string, count int) {
+	PurgedRecordsTotal.WithLabelValues(kind).Add(float64(count))
+}
@@ -0,0 +1,46 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	PostReactionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "post_reactions_total",
+			Help: "Total number of post reaction changes by reaction and action (set, remove)",
+		},
+		[]string{"reaction", "action"},
+	)
+
+	ReactionCountersFlushTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reaction_counters_flush_total",
+			Help: "Total number of reaction counter flush batches by result",
+		},
+		[]string{"result"},
+	)
+
+	ReactionCountersFlushedPosts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "reaction_counters_flushed_posts_total",
+			Help: "Total number of posts whose reaction counters were recounted in PostgreSQL",
+		},
+	)
+)
+
+// RecordPostReaction увеличивает счетчик изменений реакций
+func RecordPostReaction(reaction, action string) {
+	PostReactionsTotal.WithLabelValues(reaction, action).Inc()
+}
+
+// RecordReactionFlush записывает результат сохранения порции счетчиков реакций
+func RecordReactionFlush(posts int, success bool) {
+	if !success {
+		ReactionCountersFlushTotal.WithLabelValues("failed").Inc()
+		return
+	}
+	ReactionCountersFlushTotal.WithLabelValues("success").Inc()
+	ReactionCountersFlushedPosts.Add(float64(posts))
+}
@@ -0,0 +1,67 @@
+package monitoring
+
+import (
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	BuildInfo = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "api_build_info",
+			Help: "Build information of the API service, always 1",
+		},
+		[]string{"version", "commit", "build_time", "go_version"},
+	)
+
+	UsersCount = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "users_count",
+			Help: "Current number of users",
+		},
+	)
+
+	PostsCount = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "posts_count",
+			Help: "Current number of posts",
+		},
+	)
+
+	FriendshipsCount = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "friendships_count",
+			Help: "Current number of friendships",
+		},
+	)
+)
+
+// RegisterRuntimeCollectors заменяет стандартный сборщик метрик Go расширенным:
+// помимо go_goroutines и go_memstats_* экспортируются метрики runtime/metrics
+// по сборке мусора, памяти и планировщику. Метрики процесса (process_*: CPU, RSS,
+// открытые файлы) собирает стандартный реестр.
+func RegisterRuntimeCollectors() {
+	prometheus.Unregister(collectors.NewGoCollector())
+	prometheus.MustRegister(collectors.NewGoCollector(
+		collectors.WithGoCollectorRuntimeMetrics(
+			collectors.MetricsGC,
+			collectors.MetricsMemory,
+			collectors.MetricsScheduler,
+		),
+	))
+}
+
+// SetBuildInfo записывает версию, коммит и время сборки сервиса
+func SetBuildInfo(version, commit, buildTime string) {
+	BuildInfo.WithLabelValues(version, commit, buildTime, runtime.Version()).Set(1)
+}
+
+// SetBusinessTotals записывает количество пользователей, постов и дружб
+func SetBusinessTotals(users, posts, friendships int) {
+	UsersCount.Set(float64(users))
+	PostsCount.Set(float64(posts))
+	FriendshipsCount.Set(float64(friendships))
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"api/internal/apperror"
+	"api/internal/database"
+	"api/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var errAttachmentNotFound = apperror.NotFound(apperror.CodeAttachmentNotFound, "Attachment not found")
+
+type AttachmentRepository struct {
+	db      *database.Database
+	writeDB *database.DB
+}
+
+func NewAttachmentRepository(db *database.Database) *AttachmentRepository {
+	return &AttachmentRepository{
+		db:      db,
+		writeDB: db.Writer(),
+	}
+}
+
+// CheckAttachmentLimit проверяет, что пост принадлежит userID и к нему можно добавить вложение.
+// Это предварительная проверка до обработки и сохранения файлов; окончательно лимит
+// соблюдает CreateAttachment. Читается с primary.
+func (r *AttachmentRepository) CheckAttachmentLimit(ctx context.Context, postID, userID, maxPerPost int) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	return r.limitError(ctx, postID, userID, maxPerPost)
+}
+
+// CreateAttachment сохраняет вложение поста, если у поста меньше maxPerPost вложений.
+// Счетчик вложений увеличивается тем же запросом: параллельные загрузки ждут
+// блокировку строки поста и видят уже увеличенный счетчик.
+func (r *AttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment, maxPerPost int) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `
+        WITH post AS (
+            UPDATE posts
+            SET attachment_count = attachment_count + 1
+            WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND attachment_count < $10
+            RETURNING id
+        )
+        INSERT INTO post_attachments (post_id, user_id, blob_key, thumbnail_key, content_type, size, width, height, created_at)
+        SELECT post.id, $2, $3, $4, $5, $6, $7, $8, $9
+        FROM post
+        RETURNING id
+    `
+
+	now := time.Now()
+	err := r.writeDB.QueryRowContext(
+		ctx,
+		"create_attachment",
+		query,
+		attachment.PostID,
+		attachment.UserID,
+		attachment.BlobKey,
+		attachment.ThumbnailKey,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Width,
+		attachment.Height,
+		now,
+		maxPerPost,
+	).Scan(&attachment.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Пост удален, принадлежит другому пользователю или лимит исчерпан, пока загружался файл
+		if err := r.limitError(ctx, attachment.PostID, attachment.UserID, maxPerPost); err != nil {
+			return err
+		}
+		return errAttachmentLimit(maxPerPost)
+	}
+	if err != nil {
+		return err
+	}
+
+	attachment.CreatedAt = now
+	r.db.MarkWrite(ctx, attachment.UserID)
+	return nil
+}
+
+// GetAttachments возвращает вложения постов postIDs по постам в порядке загрузки.
+// Читается с реплики пользователя viewerID.
+func (r *AttachmentRepository) GetAttachments(ctx context.Context, viewerID int, postIDs []int) (map[int][]models.Attachment, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `
+        SELECT id, post_id, user_id, blob_key, thumbnail_key, content_type, size, width, height, created_at
+        FROM post_attachments
+        WHERE post_id = ANY($1)
+        ORDER BY post_id, id
+    `
+
+	rows, err := r.db.ReaderMaxStaleness(ctx, viewerID, postMaxStaleness).QueryContext(ctx, "get_attachments", query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make(map[int][]models.Attachment)
+	for rows.Next() {
+		var a models.Attachment
+		err := rows.Scan(
+			&a.ID, &a.PostID, &a.UserID, &a.BlobKey, &a.ThumbnailKey,
+			&a.ContentType, &a.Size, &a.Width, &a.Height, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments[a.PostID] = append(attachments[a.PostID], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// GetAttachment возвращает вложение неудаленного поста. Читается с реплики пользователя viewerID.
+func (r *AttachmentRepository) GetAttachment(ctx context.Context, attachmentID, viewerID int) (*models.Attachment, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `
+        SELECT a.id, a.post_id, a.user_id, a.blob_key, a.thumbnail_key, a.content_type, a.size, a.width, a.height, a.created_at
+        FROM post_attachments a
+        JOIN posts p ON p.id = a.post_id
+        WHERE a.id = $1 AND p.deleted_at IS NULL
+    `
+
+	var a models.Attachment
+	err := r.db.ReaderMaxStaleness(ctx, viewerID, postMaxStaleness).QueryRowContext(ctx, "get_attachment", query, attachmentID).Scan(
+		&a.ID, &a.PostID, &a.UserID, &a.BlobKey, &a.ThumbnailKey,
+		&a.ContentType, &a.Size, &a.Width, &a.Height, &a.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errAttachmentNotFound.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DeleteAttachment удаляет вложение; удалить может только автор поста.
+// Возвращает удаленное вложение, чтобы удалить его файлы.
+func (r *AttachmentRepository) DeleteAttachment(ctx context.Context, attachmentID, userID int) (*models.Attachment, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `
+        WITH deleted AS (
+            DELETE FROM post_attachments a
+            USING posts p
+            WHERE a.id = $1 AND p.id = a.post_id AND p.user_id = $2 AND p.deleted_at IS NULL
+            RETURNING a.id, a.post_id, a.blob_key, a.thumbnail_key
+        ), counted AS (
+            UPDATE posts p
+            SET attachment_count = p.attachment_count - 1
+            FROM deleted
+            WHERE p.id = deleted.post_id
+        )
+        SELECT id, post_id, blob_key, thumbnail_key FROM deleted
+    `
+
+	var a models.Attachment
+	err := r.writeDB.QueryRowContext(ctx, "delete_attachment", query, attachmentID, userID).
+		Scan(&a.ID, &a.PostID, &a.BlobKey, &a.ThumbnailKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.ownershipError(ctx, attachmentID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.db.MarkWrite(ctx, userID)
+	return &a, nil
+}
+
+// ownershipError объясняет, почему вложение не удалено: его нет (или пост удален)
+// или пост принадлежит другому пользователю. Читается с primary.
+func (r *AttachmentRepository) ownershipError(ctx context.Context, attachmentID int) error {
+	query := `
+        SELECT EXISTS(
+            SELECT 1 FROM post_attachments a
+            JOIN posts p ON p.id = a.post_id
+            WHERE a.id = $1 AND p.deleted_at IS NULL
+        )
+    `
+
+	var exists bool
+	err := r.writeDB.QueryRowContext(ctx, "attachment_exists", query, attachmentID).Scan(&exists)
+	switch {
+	case err != nil:
+		return err
+	case !exists:
+		return errAttachmentNotFound
+	default:
+		return apperror.Forbidden(apperror.CodeAttachmentForbidden, "Attachment belongs to another user's post")
+	}
+}
+
+// limitError возвращает ошибку, если пост не найден, принадлежит другому пользователю
+// или у него уже maxPerPost вложений. Читается с primary.
+func (r *AttachmentRepository) limitError(ctx context.Context, postID, userID, maxPerPost int) error {
+	var ownerID, count int
+	err := r.writeDB.QueryRowContext(ctx, "get_post_attachment_count", `SELECT user_id, attachment_count FROM posts WHERE id = $1 AND deleted_at IS NULL`, postID).
+		Scan(&ownerID, &count)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errPostNotFound
+	case err != nil:
+		return err
+	case ownerID != userID:
+		return apperror.Forbidden(apperror.CodePostForbidden, "Post belongs to another user")
+	case count >= maxPerPost:
+		return errAttachmentLimit(maxPerPost)
+	default:
+		return nil
+	}
+}
+
+func errAttachmentLimit(maxPerPost int) error {
+	return apperror.Conflict(apperror.CodeAttachmentLimit, fmt.Sprintf("Post can have at most %d attachments", maxPerPost))
+}
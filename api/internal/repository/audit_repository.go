@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"api/internal/database"
+	"api/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Допустимое отставание реплики для выборки журнала аудита
+const auditMaxStaleness = 10 * time.Second
+
+type AuditRepository struct {
+	db      *database.Database
+	writeDB *database.DB
+}
+
+func NewAuditRepository(db *database.Database) *AuditRepository {
+	return &AuditRepository{
+		db:      db,
+		writeDB: db.Writer(),
+	}
+}
+
+// CreateEntry добавляет запись в журнал аудита
+func (r *AuditRepository) CreateEntry(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+        INSERT INTO audit_log (actor_id, action, target_type, target_id, ip, user_agent, details, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `
+
+	var details []byte
+	if len(entry.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(entry.Details); err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+	}
+
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	return r.writeDB.QueryRowContext(ctx, "create_audit_entry", query,
+		entry.ActorID,
+		entry.Action,
+		nullString(entry.TargetType),
+		entry.TargetID,
+		nullString(entry.IP),
+		nullString(entry.UserAgent),
+		details,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+}
+
+// FindEntries возвращает записи журнала по фильтрам, новые первыми
+func (r *AuditRepository) FindEntries(ctx context.Context, filter *models.AuditFilter, limit, offset int) ([]models.AuditEntry, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != nil {
+		addCondition("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		addCondition("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		addCondition("target_id = $%d", *filter.TargetID)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+        SELECT id, actor_id, action, target_type, target_id, ip, user_agent, details, created_at
+        FROM audit_log
+        %s
+        ORDER BY created_at DESC, id DESC
+        LIMIT $%d OFFSET $%d
+    `, where, len(args)-1, len(args))
+
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpSearch)
+	defer cancel()
+
+	rows, err := r.db.ReaderMaxStaleness(ctx, 0, auditMaxStaleness).QueryContext(ctx, "find_audit_entries", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var (
+			entry      models.AuditEntry
+			actorID    sql.NullInt64
+			targetType sql.NullString
+			targetID   sql.NullInt64
+			ip         sql.NullString
+			userAgent  sql.NullString
+			details    []byte
+		)
+
+		err := rows.Scan(&entry.ID, &actorID, &entry.Action, &targetType, &targetID, &ip, &userAgent, &details, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			entry.ActorID = &id
+		}
+		if targetID.Valid {
+			id := int(targetID.Int64)
+			entry.TargetID = &id
+		}
+		entry.TargetType = targetType.String
+		entry.IP = ip.String
+		entry.UserAgent = userAgent.String
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &entry.Details); err != nil {
+				return nil, fmt.Errorf("failed to decode audit details: %w", err)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// DeleteEntriesBefore удаляет до batchSize записей старше before и возвращает количество удаленных.
+// Удаление порциями не держит долгих блокировок на таблице.
+func (r *AuditRepository) DeleteEntriesBefore(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	query := `
+        DELETE FROM audit_log
+        WHERE id IN (
+            SELECT id FROM audit_log
+            WHERE created_at < $1
+            ORDER BY created_at
+            LIMIT $2
+        )
+    `
+
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	result, err := r.writeDB.ExecContext(ctx, "delete_audit_entries", query, before, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// nullString сохраняет пустую строку как NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
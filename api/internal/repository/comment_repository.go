@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"api/internal/apperror"
+	"api/internal/database"
+	"api/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	errCommentNotFound      = apperror.NotFound(apperror.CodeCommentNotFound, "Comment not found")
+	errInvalidParentComment = apperror.Validation(apperror.CodeInvalidParentComment, "Parent comment must be a top-level comment of the same post")
+)
+
+type CommentRepository struct {
+	db      *database.Database
+	writeDB *database.DB
+}
+
+func NewCommentRepository(db *database.Database) *CommentRepository {
+	return &CommentRepository{
+		db:      db,
+		writeDB: db.Writer(),
+	}
+}
+
+// CreateComment создает комментарий или ответ на комментарий верхнего уровня того же поста.
+// Возвращает автора поста.
+func (r *CommentRepository) CreateComment(ctx context.Context, comment *models.Comment) (int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	// Ответ на ответ, на комментарий другого поста или комментарий к удаленному посту
+	// не вставляется: проверка видимости поста в сервисе читает с реплики и может отставать
+	query := `
+        INSERT INTO comments (post_id, user_id, parent_id, content, created_at, updated_at)
+        SELECT $1, $2, $3, $4, $5, $5
+        WHERE EXISTS (SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL)
+          AND ($3::INTEGER IS NULL OR EXISTS (
+            SELECT 1 FROM comments WHERE id = $3 AND post_id = $1 AND parent_id IS NULL
+          ))
+        RETURNING id, (SELECT user_id FROM posts WHERE id = $1)
+    `
+
+	now := time.Now()
+	var postAuthorID int
+	err := r.writeDB.QueryRowContext(
+		ctx,
+		"create_comment",
+		query,
+		comment.PostID,
+		comment.UserID,
+		comment.ParentID,
+		comment.Content,
+		now,
+	).Scan(&comment.ID, &postAuthorID)
+	switch {
+	case database.IsForeignKeyViolation(err):
+		return 0, errPostNotFound.Wrap(err)
+	case errors.Is(err, sql.ErrNoRows):
+		// Комментария-родителя нет, но сначала проверяем сам пост, чтобы ответить 404
+		if err := r.postExists(ctx, comment.PostID); err != nil {
+			return 0, err
+		}
+		return 0, errInvalidParentComment
+	case err != nil:
+		return 0, err
+	}
+
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	r.db.MarkWrite(ctx, comment.UserID)
+	return postAuthorID, nil
+}
+
+// GetComments возвращает комментарии верхнего уровня поста (parentID == nil) или ответы
+// на комментарий parentID в порядке создания, начиная после комментария afterID.
+// Комментарии удаленных пользователей не выдаются и не учитываются в счетчиках.
+// Комментарии читаются с реплики пользователя viewerID.
+func (r *CommentRepository) GetComments(ctx context.Context, viewerID, postID int, parentID *int, afterID, limit int) ([]models.CommentResponse, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	readDB := r.db.ReaderMaxStaleness(ctx, viewerID, postMaxStaleness)
+
+	// Отдельные условия для NULL и значения, чтобы оба варианта использовали индексы
+	parentCondition := "c.parent_id IS NULL"
+	args := []interface{}{postID, afterID, limit}
+	if parentID != nil {
+		parentCondition = "c.parent_id = $4"
+		args = append(args, *parentID)
+	}
+
+	query := fmt.Sprintf(`
+        SELECT c.id, c.post_id, c.user_id, c.parent_id, c.content, c.created_at, c.updated_at,
+               (SELECT COUNT(*) FROM comments r JOIN users ru ON ru.id = r.user_id WHERE r.parent_id = c.id AND ru.deleted_at IS NULL),
+               u.username, u.email, u.first_name, u.last_name,
+               u.birth_date, u.gender, u.interests, u.city, u.created_at
+        FROM comments c
+        JOIN users u ON c.user_id = u.id
+        WHERE c.post_id = $1 AND %s AND c.id > $2 AND u.deleted_at IS NULL
+        ORDER BY c.id
+        LIMIT $3
+    `, parentCondition)
+
+	rows, err := readDB.QueryContext(ctx, "get_comments", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.CommentResponse{}
+	for rows.Next() {
+		var comment models.CommentResponse
+		var parent sql.NullInt64
+		var user models.UserResponse
+
+		err := rows.Scan(
+			&comment.ID, &comment.PostID, &comment.UserID, &parent, &comment.Content,
+			&comment.CreatedAt, &comment.UpdatedAt, &comment.RepliesCount,
+			&user.Username, &user.Email, &user.FirstName, &user.LastName,
+			&user.BirthDate, &user.Gender, &user.Interests, &user.City, &user.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.Valid {
+			id := int(parent.Int64)
+			comment.ParentID = &id
+		}
+		comment.User = user
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Пустая первая страница - повод проверить, существует ли пост
+	if len(comments) == 0 && afterID == 0 {
+		var exists bool
+		existsQuery := `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL)`
+		if err := readDB.QueryRowContext(ctx, "post_exists", existsQuery, postID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errPostNotFound
+		}
+	}
+
+	return comments, nil
+}
+
+// UpdateComment изменяет текст комментария; изменять может только автор комментария.
+// Комментарии удаленного поста не изменяются.
+func (r *CommentRepository) UpdateComment(ctx context.Context, commentID, userID int, req *models.UpdateCommentRequest) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `
+        UPDATE comments c SET content = $1, updated_at = $2
+        FROM posts p
+        WHERE c.id = $3 AND c.user_id = $4 AND p.id = c.post_id AND p.deleted_at IS NULL
+    `
+
+	result, err := r.writeDB.ExecContext(ctx, "update_comment", query, req.Content, time.Now(), commentID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return r.ownershipError(ctx, commentID)
+	}
+
+	r.db.MarkWrite(ctx, userID)
+	return nil
+}
+
+// DeleteComment удаляет комментарий вместе с ответами. Удалить может автор комментария
+// или автор поста, если пост не удален. Возвращает пост и его автора.
+func (r *CommentRepository) DeleteComment(ctx context.Context, commentID, userID int) (postID, postAuthorID int, err error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `
+        DELETE FROM comments c
+        USING posts p
+        WHERE c.id = $1 AND p.id = c.post_id AND p.deleted_at IS NULL AND (c.user_id = $2 OR p.user_id = $2)
+        RETURNING c.post_id, p.user_id
+    `
+
+	err = r.writeDB.QueryRowContext(ctx, "delete_comment", query, commentID, userID).Scan(&postID, &postAuthorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, r.ownershipError(ctx, commentID)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r.db.MarkWrite(ctx, userID)
+	return postID, postAuthorID, nil
+}
+
+// ownershipError объясняет, почему изменение комментария не затронуло ни одной строки:
+// комментария нет (или удален его пост) или у пользователя нет прав на него. Читается с primary.
+func (r *CommentRepository) ownershipError(ctx context.Context, commentID int) error {
+	query := `
+        SELECT EXISTS(
+            SELECT 1 FROM comments c JOIN posts p ON p.id = c.post_id
+            WHERE c.id = $1 AND p.deleted_at IS NULL
+        )
+    `
+
+	var exists bool
+	err := r.writeDB.QueryRowContext(ctx, "comment_exists", query, commentID).Scan(&exists)
+	switch {
+	case err != nil:
+		return err
+	case !exists:
+		return errCommentNotFound
+	default:
+		return apperror.Forbidden(apperror.CodeCommentForbidden, "Comment belongs to another user")
+	}
+}
+
+// postExists возвращает errPostNotFound, если поста нет или он удален. Читается с primary.
+func (r *CommentRepository) postExists(ctx context.Context, postID int) error {
+	var exists bool
+	err := r.writeDB.QueryRowContext(ctx, "post_exists", `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL)`, postID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errPostNotFound
+	}
+	return nil
+}
@@ -1,39 +1,54 @@
 package repository
 
 import (
+	"api/internal/apperror"
+	"api/internal/database"
 	"api/internal/models"
-	"database/sql"
-	"fmt"
+	"context"
 	"time"
 )
 
+// Допустимое отставание реплики для запросов о друзьях
+const (
+	friendshipMaxStaleness  = 2 * time.Second
+	friendsListMaxStaleness = 10 * time.Second
+)
+
 type FriendRepository struct {
-	writeDB *sql.DB
-	readDB  *sql.DB
+	db      *database.Database
+	writeDB *database.DB
 }
 
-func NewFriendRepository(writeDB, readDB *sql.DB) *FriendRepository {
+func NewFriendRepository(db *database.Database) *FriendRepository {
 	return &FriendRepository{
-		writeDB: writeDB,
-		readDB:  readDB,
+		db:      db,
+		writeDB: db.Writer(),
 	}
 }
 
 // AddFriend добавляет друга
-func (r *FriendRepository) AddFriend(userID, friendID int) error {
+func (r *FriendRepository) AddFriend(ctx context.Context, userID, friendID int) error {
 	// Проверяем, что пользователь не пытается добавить сам себя
 	if userID == friendID {
-		return fmt.Errorf("cannot add yourself as a friend")
+		return apperror.Validation(apperror.CodeCannotFriendSelf, "Cannot add yourself as a friend")
 	}
 
 	// Проверяем существование пользователя
-	if exists, err := r.userExists(friendID); err != nil || !exists {
-		return fmt.Errorf("friend user does not exist")
+	exists, err := r.userExists(ctx, userID, friendID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apperror.NotFound(apperror.CodeUserNotFound, "Friend user does not exist")
 	}
 
 	// Проверяем, не добавлен ли уже друг
-	if isFriend, err := r.IsFriend(userID, friendID); err != nil || isFriend {
-		return fmt.Errorf("users are already friends")
+	isFriend, err := r.IsFriend(ctx, userID, friendID)
+	if err != nil {
+		return err
+	}
+	if isFriend {
+		return apperror.Conflict(apperror.CodeAlreadyFriends, "Users are already friends")
 	}
 
 	query := `
@@ -41,19 +56,30 @@ func (r *FriendRepository) AddFriend(userID, friendID int) error {
         VALUES ($1, $2, $3), ($2, $1, $3)
     `
 
-	_, err := r.writeDB.Exec(query, userID, friendID, time.Now())
-	return err
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	_, err = r.writeDB.ExecContext(ctx, "add_friend", query, userID, friendID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	r.db.MarkWrite(ctx, userID)
+	return nil
 }
 
 // DeleteFriend удаляет друга
-func (r *FriendRepository) DeleteFriend(userID, friendID int) error {
+func (r *FriendRepository) DeleteFriend(ctx context.Context, userID, friendID int) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
 	query := `
         DELETE FROM friends 
         WHERE (user_id = $1 AND friend_id = $2) 
            OR (user_id = $2 AND friend_id = $1)
     `
 
-	result, err := r.writeDB.Exec(query, userID, friendID)
+	result, err := r.writeDB.ExecContext(ctx, "delete_friend", query, userID, friendID)
 	if err != nil {
 		return err
 	}
@@ -64,14 +90,18 @@ func (r *FriendRepository) DeleteFriend(userID, friendID int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("friendship not found")
+		return apperror.NotFound(apperror.CodeFriendshipNotFound, "Friendship not found")
 	}
 
+	r.db.MarkWrite(ctx, userID)
 	return nil
 }
 
 // IsFriend проверяет, являются ли пользователи друзьями
-func (r *FriendRepository) IsFriend(userID, friendID int) (bool, error) {
+func (r *FriendRepository) IsFriend(ctx context.Context, userID, friendID int) (bool, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
 	query := `
         SELECT EXISTS(
             SELECT 1 FROM friends 
@@ -80,23 +110,26 @@ func (r *FriendRepository) IsFriend(userID, friendID int) (bool, error) {
     `
 
 	var exists bool
-	err := r.readDB.QueryRow(query, userID, friendID).Scan(&exists)
+	err := r.db.ReaderMaxStaleness(ctx, userID, friendshipMaxStaleness).QueryRowContext(ctx, "is_friend", query, userID, friendID).Scan(&exists)
 	return exists, err
 }
 
 // GetFriends возвращает список друзей пользователя
-func (r *FriendRepository) GetFriends(userID int) ([]models.FriendResponse, error) {
+func (r *FriendRepository) GetFriends(ctx context.Context, userID int) ([]models.FriendResponse, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
 	query := `
         SELECT f.id, f.user_id, f.friend_id, f.created_at,
                u.username, u.email, u.first_name, u.last_name,
                u.birth_date, u.gender, u.interests, u.city, u.created_at
         FROM friends f
         JOIN users u ON f.friend_id = u.id
-        WHERE f.user_id = $1
+        WHERE f.user_id = $1 AND u.deleted_at IS NULL
         ORDER BY f.created_at DESC
     `
 
-	rows, err := r.readDB.Query(query, userID)
+	rows, err := r.db.ReaderMaxStaleness(ctx, userID, friendsListMaxStaleness).QueryContext(ctx, "get_friends", query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -123,9 +156,34 @@ func (r *FriendRepository) GetFriends(userID int) ([]models.FriendResponse, erro
 	return friends, nil
 }
 
+// GetFriendIDs возвращает ID друзей пользователя
+func (r *FriendRepository) GetFriendIDs(ctx context.Context, userID int) ([]int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `SELECT friend_id FROM friends WHERE user_id = $1`
+
+	rows, err := r.db.ReaderMaxStaleness(ctx, userID, friendsListMaxStaleness).QueryContext(ctx, "get_friend_ids", query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var friendIDs []int
+	for rows.Next() {
+		var friendID int
+		if err := rows.Scan(&friendID); err != nil {
+			return nil, err
+		}
+		friendIDs = append(friendIDs, friendID)
+	}
+
+	return friendIDs, rows.Err()
+}
+
 // GetFriendshipStatus возвращает статус дружбы между пользователями
-func (r *FriendRepository) GetFriendshipStatus(userID, friendID int) (*models.FriendshipStatus, error) {
-	isFriend, err := r.IsFriend(userID, friendID)
+func (r *FriendRepository) GetFriendshipStatus(ctx context.Context, userID, friendID int) (*models.FriendshipStatus, error) {
+	isFriend, err := r.IsFriend(ctx, userID, friendID)
 	if err != nil {
 		return nil, err
 	}
@@ -136,9 +194,13 @@ func (r *FriendRepository) GetFriendshipStatus(userID, friendID int) (*models.Fr
 	}, nil
 }
 
-func (r *FriendRepository) userExists(userID int) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`
+// userExists проверяет существование неудаленного пользователя targetID по запросу пользователя viewerID
+func (r *FriendRepository) userExists(ctx context.Context, viewerID, targetID int) (bool, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
 	var exists bool
-	err := r.readDB.QueryRow(query, userID).Scan(&exists)
+	err := r.db.ReaderMaxStaleness(ctx, viewerID, friendshipMaxStaleness).QueryRowContext(ctx, "friend_user_exists", query, targetID).Scan(&exists)
 	return exists, err
 }
@@ -1,94 +1,228 @@
 package repository
 
 import (
+	"api/internal/apperror"
+	"api/internal/database"
 	"api/internal/models"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+// Допустимое отставание реплики для запросов постов
+const (
+	postMaxStaleness = 5 * time.Second
+	feedMaxStaleness = 30 * time.Second
 )
 
+var errPostNotFound = apperror.NotFound(apperror.CodePostNotFound, "Post not found")
+
 type PostRepository struct {
-	writeDB *sql.DB
-	readDB  *sql.DB
+	db      *database.Database
+	writeDB *database.DB
 }
 
-func NewPostRepository(writeDB, readDB *sql.DB) *PostRepository {
+func NewPostRepository(db *database.Database) *PostRepository {
 	return &PostRepository{
-		writeDB: writeDB,
-		readDB:  readDB,
+		db:      db,
+		writeDB: db.Writer(),
 	}
 }
 
 // CreatePost создает новый пост
-func (r *PostRepository) CreatePost(post *models.Post) error {
+func (r *PostRepository) CreatePost(ctx context.Context, post *models.Post) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
 	query := `
-        INSERT INTO posts (user_id, title, content, created_at, updated_at) 
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id
+        INSERT INTO posts (user_id, title, content, visibility, created_at, updated_at) 
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, version
     `
 
 	now := time.Now()
-	err := r.writeDB.QueryRow(
+	err := r.writeDB.QueryRowContext(
+		ctx,
+		"create_post",
 		query,
 		post.UserID,
 		post.Title,
 		post.Content,
+		post.Visibility,
 		now,
 		now,
-	).Scan(&post.ID)
+	).Scan(&post.ID, &post.Version)
+	if err != nil {
+		return err
+	}
 
-	return err
+	r.db.MarkWrite(ctx, post.UserID)
+	return nil
 }
 
-// GetPost возвращает пост по ID
-func (r *PostRepository) GetPost(postID int) (*models.PostResponse, error) {
+// GetPost возвращает пост по ID (viewerID - пользователь, выполняющий запрос)
+func (r *PostRepository) GetPost(ctx context.Context, postID, viewerID int) (*models.PostResponse, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
 	query := `
-        SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at,
+        SELECT p.id, p.user_id, p.title, p.content, p.visibility, p.created_at, p.updated_at, p.version,
+               (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL),
                u.username, u.email, u.first_name, u.last_name,
                u.birth_date, u.gender, u.interests, u.city, u.created_at
         FROM posts p
         JOIN users u ON p.user_id = u.id
-        WHERE p.id = $1
+        WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
     `
 
 	var post models.PostResponse
 	var user models.UserResponse
+	var version int
 
-	err := r.readDB.QueryRow(query, postID).Scan(
-		&post.ID, &post.UserID, &post.Title, &post.Content,
-		&post.CreatedAt, &post.UpdatedAt,
+	err := r.db.ReaderMaxStaleness(ctx, viewerID, postMaxStaleness).QueryRowContext(ctx, "get_post", query, postID).Scan(
+		&post.ID, &post.UserID, &post.Title, &post.Content, &post.Visibility,
+		&post.CreatedAt, &post.UpdatedAt, &version, &post.CommentsCount,
 		&user.Username, &user.Email, &user.FirstName, &user.LastName,
 		&user.BirthDate, &user.Gender, &user.Interests, &user.City, &user.CreatedAt,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("post not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errPostNotFound.Wrap(err)
 		}
 		return nil, err
 	}
 
 	post.User = user
+	post.SetVersion(version)
 	return &post, nil
 }
 
-// UpdatePost обновляет пост
-func (r *PostRepository) UpdatePost(postID, userID int, updateReq *models.UpdatePostRequest) error {
+// GetPostVisibility возвращает автора и видимость поста для проверки доступа
+func (r *PostRepository) GetPostVisibility(ctx context.Context, postID, viewerID int) (int, models.PostVisibility, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `
+        SELECT p.user_id, p.visibility
+        FROM posts p
+        JOIN users u ON p.user_id = u.id
+        WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+    `
+
+	var authorID int
+	var visibility models.PostVisibility
+	err := r.db.ReaderMaxStaleness(ctx, viewerID, postMaxStaleness).QueryRowContext(ctx, "get_post_visibility", query, postID).Scan(&authorID, &visibility)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", errPostNotFound.Wrap(err)
+		}
+		return 0, "", err
+	}
+
+	return authorID, visibility, nil
+}
+
+// UpdatePost обновляет пост и сохраняет заменяемую версию в post_revisions.
+// Если expectedVersions не пустой, пост обновляется, только пока его версия входит в expectedVersions.
+// Возвращает новую версию поста.
+func (r *PostRepository) UpdatePost(ctx context.Context, postID, userID int, expectedVersions []int, updateReq *models.UpdatePostRequest) (int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	// Строка блокируется до сохранения версии: параллельная правка дождется этого
+	// обновления и перепроверит версию, а без ожидаемой версии сохранит уже новую
 	query := `
-        UPDATE posts 
-        SET title = COALESCE($1, title),
-            content = COALESCE($2, content),
-            updated_at = $3
-        WHERE id = $4 AND user_id = $5
+        WITH prev AS (
+            SELECT id, version, title, content, visibility, updated_at
+            FROM posts
+            WHERE id = $5 AND user_id = $6 AND deleted_at IS NULL AND (cardinality($7::int[]) = 0 OR version = ANY($7))
+            FOR UPDATE
+        ), revision AS (
+            INSERT INTO post_revisions (post_id, version, title, content, visibility, created_at, replaced_at)
+            SELECT id, version, title, content, visibility, updated_at, $4
+            FROM prev
+        )
+        UPDATE posts p
+        SET title = COALESCE($1, p.title),
+            content = COALESCE($2, p.content),
+            visibility = COALESCE($3, p.visibility),
+            updated_at = $4,
+            version = p.version + 1
+        FROM prev
+        WHERE p.id = prev.id
+        RETURNING p.version
     `
 
-	result, err := r.writeDB.Exec(
+	var version int
+	err := r.writeDB.QueryRowContext(
+		ctx,
+		"update_post",
 		query,
 		updateReq.Title,
 		updateReq.Content,
+		updateReq.Visibility,
 		time.Now(),
 		postID,
 		userID,
-	)
+		pq.Array(expectedVersions),
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, r.updateError(ctx, postID, userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	r.db.MarkWrite(ctx, userID)
+	return version, nil
+}
+
+// GetPostRevisions возвращает предыдущие версии поста, начиная с последней
+func (r *PostRepository) GetPostRevisions(ctx context.Context, postID, viewerID int) ([]models.PostRevision, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `
+        SELECT version, title, content, visibility, created_at, replaced_at
+        FROM post_revisions
+        WHERE post_id = $1
+        ORDER BY version DESC
+    `
+
+	rows, err := r.db.ReaderMaxStaleness(ctx, viewerID, postMaxStaleness).QueryContext(ctx, "get_post_revisions", query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.PostRevision{}
+	for rows.Next() {
+		var rev models.PostRevision
+		if err := rows.Scan(&rev.Version, &rev.Title, &rev.Content, &rev.Visibility, &rev.CreatedAt, &rev.ReplacedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// DeletePost помечает пост удаленным. Пост со всеми комментариями, реакциями
+// и вложениями остается в БД до окончательного удаления (PurgeDeletedPosts).
+func (r *PostRepository) DeletePost(ctx context.Context, postID, userID int) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `UPDATE posts SET deleted_at = $3 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+
+	result, err := r.writeDB.ExecContext(ctx, "delete_post", query, postID, userID, time.Now())
 	if err != nil {
 		return err
 	}
@@ -99,17 +233,21 @@ func (r *PostRepository) UpdatePost(postID, userID int, updateReq *models.Update
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("post not found or access denied")
+		return r.ownershipError(ctx, postID)
 	}
 
+	r.db.MarkWrite(ctx, userID)
 	return nil
 }
 
-// DeletePost удаляет пост
-func (r *PostRepository) DeletePost(postID, userID int) error {
-	query := `DELETE FROM posts WHERE id = $1 AND user_id = $2`
+// RestorePost снимает пометку об удалении с поста автора userID, удаленного после deletedAfter
+func (r *PostRepository) RestorePost(ctx context.Context, postID, userID int, deletedAfter time.Time) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
 
-	result, err := r.writeDB.Exec(query, postID, userID)
+	query := `UPDATE posts SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at > $3`
+
+	result, err := r.writeDB.ExecContext(ctx, "restore_post", query, postID, userID, deletedAfter)
 	if err != nil {
 		return err
 	}
@@ -120,35 +258,138 @@ func (r *PostRepository) DeletePost(postID, userID int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("post not found or access denied")
+		return r.restoreError(ctx, postID, userID)
 	}
 
+	r.db.MarkWrite(ctx, userID)
 	return nil
 }
 
-// GetUserPosts возвращает посты пользователя
-func (r *PostRepository) GetUserPosts(userID, limit, offset int) ([]models.PostResponse, int, error) {
+// PurgeDeletedPosts окончательно удаляет до batchSize постов, удаленных раньше before,
+// вместе с комментариями, реакциями и записями о вложениях.
+// Возвращает количество удаленных постов и ключи файлов их вложений.
+func (r *PostRepository) PurgeDeletedPosts(ctx context.Context, before time.Time, batchSize int) (int, []string, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	// Основной запрос видит вложения до каскадного удаления, поэтому ключи файлов
+	// возвращаются тем же запросом
+	query := `
+        WITH purged AS (
+            DELETE FROM posts
+            WHERE id IN (
+                SELECT id FROM posts
+                WHERE deleted_at < $1
+                ORDER BY deleted_at
+                LIMIT $2
+            )
+            RETURNING id
+        )
+        SELECT p.id, a.blob_key, a.thumbnail_key
+        FROM purged p
+        LEFT JOIN post_attachments a ON a.post_id = p.id
+    `
+
+	rows, err := r.writeDB.QueryContext(ctx, "purge_deleted_posts", query, before, batchSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	return scanPurged(rows)
+}
+
+// restoreError объясняет, почему пост не восстановлен. Удаленные посты других
+// пользователей выглядят несуществующими. Читается с primary.
+func (r *PostRepository) restoreError(ctx context.Context, postID, userID int) error {
+	var ownerID int
+	var deletedAt sql.NullTime
+	err := r.writeDB.QueryRowContext(ctx, "get_post_deleted_at", `SELECT user_id, deleted_at FROM posts WHERE id = $1`, postID).Scan(&ownerID, &deletedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errPostNotFound
+	case err != nil:
+		return err
+	case ownerID != userID && deletedAt.Valid:
+		return errPostNotFound
+	case ownerID != userID:
+		return apperror.Forbidden(apperror.CodePostForbidden, "Post belongs to another user")
+	case !deletedAt.Valid:
+		return apperror.Conflict(apperror.CodePostNotDeleted, "Post is not deleted")
+	default:
+		return apperror.Conflict(apperror.CodeRestoreWindowExpired, "Post can no longer be restored")
+	}
+}
+
+// ownershipError объясняет, почему изменение поста не затронуло ни одной строки:
+// поста нет (в том числе удален) или он принадлежит другому пользователю.
+// Владелец читается с primary, чтобы не получить устаревший ответ с реплики.
+func (r *PostRepository) ownershipError(ctx context.Context, postID int) error {
+	var ownerID int
+	err := r.writeDB.QueryRowContext(ctx, "get_post_owner", `SELECT user_id FROM posts WHERE id = $1 AND deleted_at IS NULL`, postID).Scan(&ownerID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errPostNotFound
+	case err != nil:
+		return err
+	default:
+		return apperror.Forbidden(apperror.CodePostForbidden, "Post belongs to another user")
+	}
+}
+
+// updateError объясняет, почему пост не обновлен: его нет, он принадлежит
+// другому пользователю или его версия изменилась. Читается с primary.
+func (r *PostRepository) updateError(ctx context.Context, postID, userID int) error {
+	var ownerID, version int
+	err := r.writeDB.QueryRowContext(ctx, "get_post_version", `SELECT user_id, version FROM posts WHERE id = $1 AND deleted_at IS NULL`, postID).Scan(&ownerID, &version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errPostNotFound
+	case err != nil:
+		return err
+	case ownerID != userID:
+		return apperror.Forbidden(apperror.CodePostForbidden, "Post belongs to another user")
+	default:
+		return apperror.PreconditionFailed(apperror.CodePostModified, fmt.Sprintf("Post was modified, current version is %d", version))
+	}
+}
+
+// GetUserPosts возвращает посты пользователя userID с видимостью из visibilities
+// по запросу пользователя viewerID
+func (r *PostRepository) GetUserPosts(ctx context.Context, viewerID, userID int, visibilities []models.PostVisibility, limit, offset int) ([]models.PostResponse, int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	readDB := r.db.ReaderMaxStaleness(ctx, viewerID, feedMaxStaleness)
+	visibilityArg := pq.Array(visibilityStrings(visibilities))
+
 	// Счетчик общего количества
 	var total int
-	countQuery := `SELECT COUNT(*) FROM posts WHERE user_id = $1`
-	err := r.readDB.QueryRow(countQuery, userID).Scan(&total)
+	countQuery := `
+        SELECT COUNT(*)
+        FROM posts p
+        JOIN users u ON p.user_id = u.id
+        WHERE p.user_id = $1 AND p.visibility = ANY($2) AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+    `
+	err := readDB.QueryRowContext(ctx, "count_user_posts", countQuery, userID, visibilityArg).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Получение постов
 	query := `
-        SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at,
+        SELECT p.id, p.user_id, p.title, p.content, p.visibility, p.created_at, p.updated_at, p.version,
+               (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL),
                u.username, u.email, u.first_name, u.last_name,
                u.birth_date, u.gender, u.interests, u.city, u.created_at
         FROM posts p
         JOIN users u ON p.user_id = u.id
-        WHERE p.user_id = $1
+        WHERE p.user_id = $1 AND p.visibility = ANY($2) AND p.deleted_at IS NULL AND u.deleted_at IS NULL
         ORDER BY p.created_at DESC
-        LIMIT $2 OFFSET $3
+        LIMIT $3 OFFSET $4
     `
 
-	rows, err := r.readDB.Query(query, userID, limit, offset)
+	rows, err := readDB.QueryContext(ctx, "get_user_posts", query, userID, visibilityArg, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -158,10 +399,11 @@ func (r *PostRepository) GetUserPosts(userID, limit, offset int) ([]models.PostR
 	for rows.Next() {
 		var post models.PostResponse
 		var user models.UserResponse
+		var version int
 
 		err := rows.Scan(
-			&post.ID, &post.UserID, &post.Title, &post.Content,
-			&post.CreatedAt, &post.UpdatedAt,
+			&post.ID, &post.UserID, &post.Title, &post.Content, &post.Visibility,
+			&post.CreatedAt, &post.UpdatedAt, &version, &post.CommentsCount,
 			&user.Username, &user.Email, &user.FirstName, &user.LastName,
 			&user.BirthDate, &user.Gender, &user.Interests, &user.City, &user.CreatedAt,
 		)
@@ -170,41 +412,49 @@ func (r *PostRepository) GetUserPosts(userID, limit, offset int) ([]models.PostR
 		}
 
 		post.User = user
+		post.SetVersion(version)
 		posts = append(posts, post)
 	}
 
 	return posts, total, nil
 }
 
-// GetFriendsPosts возвращает посты друзей пользователя (лента)
-func (r *PostRepository) GetFriendsPosts(userID, limit, offset int) ([]models.PostResponse, int, error) {
+// GetFriendsPosts возвращает посты друзей пользователя (лента): публичные и для друзей
+func (r *PostRepository) GetFriendsPosts(ctx context.Context, userID, limit, offset int) ([]models.PostResponse, int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	readDB := r.db.ReaderMaxStaleness(ctx, userID, feedMaxStaleness)
+
 	// Счетчик общего количества
 	var total int
 	countQuery := `
         SELECT COUNT(*) 
         FROM posts p
         JOIN friends f ON p.user_id = f.friend_id
-        WHERE f.user_id = $1
+        JOIN users u ON p.user_id = u.id
+        WHERE f.user_id = $1 AND p.visibility <> 'private' AND p.deleted_at IS NULL AND u.deleted_at IS NULL
     `
-	err := r.readDB.QueryRow(countQuery, userID).Scan(&total)
+	err := readDB.QueryRowContext(ctx, "count_friends_posts", countQuery, userID).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Получение постов друзей
 	query := `
-        SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at,
+        SELECT p.id, p.user_id, p.title, p.content, p.visibility, p.created_at, p.updated_at, p.version,
+               (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL),
                u.username, u.email, u.first_name, u.last_name,
                u.birth_date, u.gender, u.interests, u.city, u.created_at
         FROM posts p
         JOIN friends f ON p.user_id = f.friend_id
         JOIN users u ON p.user_id = u.id
-        WHERE f.user_id = $1
+        WHERE f.user_id = $1 AND p.visibility <> 'private' AND p.deleted_at IS NULL AND u.deleted_at IS NULL
         ORDER BY p.created_at DESC
         LIMIT $2 OFFSET $3
     `
 
-	rows, err := r.readDB.Query(query, userID, limit, offset)
+	rows, err := readDB.QueryContext(ctx, "get_friends_posts", query, userID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -214,10 +464,11 @@ func (r *PostRepository) GetFriendsPosts(userID, limit, offset int) ([]models.Po
 	for rows.Next() {
 		var post models.PostResponse
 		var user models.UserResponse
+		var version int
 
 		err := rows.Scan(
-			&post.ID, &post.UserID, &post.Title, &post.Content,
-			&post.CreatedAt, &post.UpdatedAt,
+			&post.ID, &post.UserID, &post.Title, &post.Content, &post.Visibility,
+			&post.CreatedAt, &post.UpdatedAt, &version, &post.CommentsCount,
 			&user.Username, &user.Email, &user.FirstName, &user.LastName,
 			&user.BirthDate, &user.Gender, &user.Interests, &user.City, &user.CreatedAt,
 		)
@@ -226,8 +477,39 @@ func (r *PostRepository) GetFriendsPosts(userID, limit, offset int) ([]models.Po
 		}
 
 		post.User = user
+		post.SetVersion(version)
 		posts = append(posts, post)
 	}
 
 	return posts, total, nil
 }
+
+// scanPurged читает результат окончательного удаления: ID удаленного объекта
+// и ключи файлов вложений (NULL, если вложений нет)
+func scanPurged(rows *database.Rows) (int, []string, error) {
+	purged := make(map[int]struct{})
+	var keys []string
+	for rows.Next() {
+		var id int
+		var blobKey, thumbnailKey sql.NullString
+		if err := rows.Scan(&id, &blobKey, &thumbnailKey); err != nil {
+			return 0, nil, err
+		}
+		purged[id] = struct{}{}
+		if blobKey.Valid {
+			keys = append(keys, blobKey.String, thumbnailKey.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	return len(purged), keys, nil
+}
+
+func visibilityStrings(visibilities []models.PostVisibility) []string {
+	values := make([]string, len(visibilities))
+	for i, visibility := range visibilities {
+		values[i] = string(visibility)
+	}
+	return values
+}
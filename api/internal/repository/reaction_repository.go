@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"api/internal/database"
+	"api/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ReactionRepository struct {
+	db      *database.Database
+	writeDB *database.DB
+}
+
+func NewReactionRepository(db *database.Database) *ReactionRepository {
+	return &ReactionRepository{
+		db:      db,
+		writeDB: db.Writer(),
+	}
+}
+
+// SetReaction ставит или меняет реакцию пользователя на пост.
+// Возвращает прежнюю реакцию (пустую, если ее не было).
+func (r *ReactionRepository) SetReaction(ctx context.Context, postID, userID int, reaction models.ReactionType) (models.ReactionType, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `
+        WITH previous AS (
+            SELECT reaction FROM post_reactions WHERE post_id = $1 AND user_id = $2
+        ), upserted AS (
+            INSERT INTO post_reactions (post_id, user_id, reaction, created_at)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (post_id, user_id) DO UPDATE
+            SET reaction = EXCLUDED.reaction, created_at = EXCLUDED.created_at
+            WHERE post_reactions.reaction <> EXCLUDED.reaction
+        )
+        SELECT (SELECT reaction FROM previous)
+    `
+
+	var previous sql.NullString
+	err := r.writeDB.QueryRowContext(ctx, "set_post_reaction", query, postID, userID, reaction, time.Now()).Scan(&previous)
+	if database.IsForeignKeyViolation(err) {
+		return "", errPostNotFound.Wrap(err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	r.db.MarkWrite(ctx, userID)
+	return models.ReactionType(previous.String), nil
+}
+
+// DeleteReaction снимает реакцию пользователя с поста.
+// Возвращает снятую реакцию (пустую, если ее не было).
+func (r *ReactionRepository) DeleteReaction(ctx context.Context, postID, userID int) (models.ReactionType, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `DELETE FROM post_reactions WHERE post_id = $1 AND user_id = $2 RETURNING reaction`
+
+	var previous models.ReactionType
+	err := r.writeDB.QueryRowContext(ctx, "delete_post_reaction", query, postID, userID).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Реакции не было: повторное снятие не ошибка, если пост существует
+		var exists bool
+		existsQuery := `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`
+		if err := r.writeDB.QueryRowContext(ctx, "post_exists", existsQuery, postID).Scan(&exists); err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", errPostNotFound
+		}
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	r.db.MarkWrite(ctx, userID)
+	return previous, nil
+}
+
+// GetCounts возвращает сохраненные счетчики реакций постов по запросу пользователя viewerID
+func (r *ReactionRepository) GetCounts(ctx context.Context, viewerID int, postIDs []int) (map[int]map[models.ReactionType]int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `SELECT post_id, reaction, count FROM post_reaction_counts WHERE post_id = ANY($1)`
+
+	rows, err := r.db.ReaderMaxStaleness(ctx, viewerID, feedMaxStaleness).QueryContext(ctx, "get_reaction_counts", query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]map[models.ReactionType]int)
+	for rows.Next() {
+		var postID, count int
+		var reaction models.ReactionType
+		if err := rows.Scan(&postID, &reaction, &count); err != nil {
+			return nil, err
+		}
+		if counts[postID] == nil {
+			counts[postID] = make(map[models.ReactionType]int)
+		}
+		counts[postID][reaction] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// GetUserReactions возвращает реакции пользователя userID на посты postIDs
+func (r *ReactionRepository) GetUserReactions(ctx context.Context, userID int, postIDs []int) (map[int]models.ReactionType, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `SELECT post_id, reaction FROM post_reactions WHERE user_id = $1 AND post_id = ANY($2)`
+
+	rows, err := r.db.ReaderMaxStaleness(ctx, userID, postMaxStaleness).QueryContext(ctx, "get_user_reactions", query, userID, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := make(map[int]models.ReactionType)
+	for rows.Next() {
+		var postID int
+		var reaction models.ReactionType
+		if err := rows.Scan(&postID, &reaction); err != nil {
+			return nil, err
+		}
+		reactions[postID] = reaction
+	}
+
+	return reactions, rows.Err()
+}
+
+// GetReactedPostIDs возвращает посты, на которые реагировал пользователь. Читается с primary:
+// используется сразу после удаления или восстановления аккаунта.
+func (r *ReactionRepository) GetReactedPostIDs(ctx context.Context, userID int) ([]int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `SELECT post_id FROM post_reactions WHERE user_id = $1`
+
+	rows, err := r.writeDB.QueryContext(ctx, "get_reacted_post_ids", query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var postIDs []int
+	for rows.Next() {
+		var postID int
+		if err := rows.Scan(&postID); err != nil {
+			return nil, err
+		}
+		postIDs = append(postIDs, postID)
+	}
+
+	return postIDs, rows.Err()
+}
+
+// RecountReactions пересчитывает сохраненные счетчики постов postIDs по таблице реакций.
+// Реакции удаленных пользователей не учитываются.
+func (r *ReactionRepository) RecountReactions(ctx context.Context, postIDs []int) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `
+        WITH actual AS (
+            SELECT pr.post_id, pr.reaction, COUNT(*) AS count
+            FROM post_reactions pr
+            JOIN users u ON u.id = pr.user_id AND u.deleted_at IS NULL
+            WHERE pr.post_id = ANY($1)
+            GROUP BY pr.post_id, pr.reaction
+        ), removed AS (
+            DELETE FROM post_reaction_counts c
+            WHERE c.post_id = ANY($1)
+              AND NOT EXISTS (
+                  SELECT 1 FROM actual a WHERE a.post_id = c.post_id AND a.reaction = c.reaction
+              )
+        )
+        INSERT INTO post_reaction_counts (post_id, reaction, count)
+        SELECT post_id, reaction, count FROM actual
+        ON CONFLICT (post_id, reaction) DO UPDATE SET count = EXCLUDED.count
+    `
+
+	_, err := r.writeDB.ExecContext(ctx, "recount_post_reactions", query, pq.Array(postIDs))
+	return err
+}
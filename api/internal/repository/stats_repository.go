@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"api/internal/database"
+	"api/internal/models"
+	"context"
+	"time"
+)
+
+// Допустимое отставание реплики для агрегатов бизнес-метрик
+const statsMaxStaleness = 5 * time.Minute
+
+type StatsRepository struct {
+	db *database.Database
+}
+
+func NewStatsRepository(db *database.Database) *StatsRepository {
+	return &StatsRepository{
+		db: db,
+	}
+}
+
+// GetTotals возвращает количество пользователей, постов и дружб.
+// Дружба хранится двумя строками (в обе стороны), поэтому строки friends делятся пополам.
+// Удаленные пользователи, их посты и дружбы, а также удаленные посты не учитываются.
+func (r *StatsRepository) GetTotals(ctx context.Context) (*models.Totals, error) {
+	query := `
+        SELECT
+            (SELECT COUNT(*) FROM users WHERE deleted_at IS NULL),
+            (SELECT COUNT(*) FROM posts p
+             JOIN users u ON u.id = p.user_id AND u.deleted_at IS NULL
+             WHERE p.deleted_at IS NULL),
+            (SELECT COUNT(*) FROM friends f
+             JOIN users u ON u.id = f.user_id AND u.deleted_at IS NULL
+             JOIN users fu ON fu.id = f.friend_id AND fu.deleted_at IS NULL) / 2
+    `
+
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpSearch)
+	defer cancel()
+
+	totals := &models.Totals{}
+	err := r.db.ReaderMaxStaleness(ctx, 0, statsMaxStaleness).QueryRowContext(ctx, "get_totals", query).Scan(
+		&totals.Users,
+		&totals.Posts,
+		&totals.Friendships,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
@@ -1,31 +1,40 @@
 package repository
 
 import (
+	"api/internal/apperror"
+	"api/internal/database"
 	"api/internal/models"
-	"api/internal/monitoring"
 	"api/pkg/utils"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 )
 
+// Допустимое отставание реплики для запросов пользователей
+const (
+	userMaxStaleness   = 5 * time.Second
+	authMaxStaleness   = 2 * time.Second
+	searchMaxStaleness = time.Minute
+)
+
+var errUserNotFound = apperror.NotFound(apperror.CodeUserNotFound, "User not found")
+
 type UserRepository struct {
-	writeDB *sql.DB
-	readDB  *sql.DB
+	db      *database.Database
+	writeDB *database.DB
 }
 
-func NewUserRepository(writeDB, readDB *sql.DB) *UserRepository {
+func NewUserRepository(db *database.Database) *UserRepository {
 	return &UserRepository{
-		writeDB: writeDB,
-		readDB:  readDB,
+		db:      db,
+		writeDB: db.Writer(),
 	}
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
-	start := time.Now()
-
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
         INSERT INTO users (
             username, email, password, first_name, last_name, 
@@ -40,13 +49,14 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 		return err
 	}
 
-	// Создаем контекст с таймаутом
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Ограничиваем запрос таймаутом записи
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
 	defer cancel()
 
 	now := time.Now()
 	err = r.writeDB.QueryRowContext(
 		ctx,
+		"create_user",
 		query,
 		user.Username,
 		user.Email,
@@ -61,27 +71,28 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 		now,
 	).Scan(&user.ID)
 
-	defer func() {
-		duration := time.Since(start)
-		monitoring.ObserveDatabaseQuery("create_user", err == nil, duration)
-	}()
+	if err != nil {
+		return err
+	}
 
-	return err
+	r.db.MarkWrite(ctx, user.ID)
+	return nil
 }
 
-func (r *UserRepository) GetUserByID(id int) (*models.UserResponse, error) {
-	start := time.Now()
+func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.UserResponse, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
 
 	query := `
         SELECT 
             id, username, email, first_name, last_name, 
             birth_date, gender, interests, city, created_at
         FROM users 
-        WHERE id = $1
+        WHERE id = $1 AND deleted_at IS NULL
     `
 
 	var user models.UserResponse
-	err := r.readDB.QueryRow(query, id).Scan(
+	err := r.db.ReaderMaxStaleness(ctx, id, userMaxStaleness).QueryRowContext(ctx, "get_user_by_id", query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -94,31 +105,32 @@ func (r *UserRepository) GetUserByID(id int) (*models.UserResponse, error) {
 		&user.CreatedAt,
 	)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errUserNotFound.Wrap(err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	defer func() {
-		duration := time.Since(start)
-		monitoring.ObserveDatabaseQuery("get_user_by_id", err == nil, duration)
-	}()
-
 	return &user, nil
 }
 
-func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
-	start := time.Now()
+// GetUserByEmail возвращает пользователя для входа, в том числе удаленного:
+// вход в удаленный аккаунт отклоняется с предложением восстановить его
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
 
 	query := `
         SELECT
             id, username, email, password, first_name, last_name,
-            birth_date, gender, interests, city, created_at, updated_at
+            birth_date, gender, interests, city, created_at, updated_at, deleted_at
         FROM users
         WHERE email = $1
     `
 
 	var user models.User
-	err := r.readDB.QueryRow(query, email).Scan(
+	err := r.db.ReaderMaxStaleness(ctx, 0, authMaxStaleness).QueryRowContext(ctx, "get_user_by_email", query, email).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -131,13 +143,9 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 		&user.City,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletedAt,
 	)
 
-	defer func() {
-		duration := time.Since(start)
-		monitoring.ObserveDatabaseQuery("get_all_users", err == nil, duration)
-	}()
-
 	if err != nil {
 		return nil, err
 	}
@@ -145,28 +153,25 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetAllUsers() ([]models.UserResponse, error) {
-	start := time.Now()
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.UserResponse, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpSearch)
+	defer cancel()
 
 	query := `
         SELECT 
             id, username, email, first_name, last_name, 
             birth_date, gender, interests, city, created_at
         FROM users 
+        WHERE deleted_at IS NULL
         ORDER BY created_at DESC
     `
 
-	rows, err := r.readDB.Query(query)
+	rows, err := r.db.ReaderMaxStaleness(ctx, 0, searchMaxStaleness).QueryContext(ctx, "get_all_users", query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	defer func() {
-		duration := time.Since(start)
-		monitoring.ObserveDatabaseQuery("get_all_users", err == nil, duration)
-	}()
-
 	var users []models.UserResponse
 	for rows.Next() {
 		var user models.UserResponse
@@ -191,25 +196,28 @@ func (r *UserRepository) GetAllUsers() ([]models.UserResponse, error) {
 	return users, nil
 }
 
-func (r *UserRepository) UserExists(username string, email string) (bool, error) {
-	start := time.Now()
+// UserExists проверяет занятость имени и почты, в том числе удаленными аккаунтами:
+// их можно восстановить до окончательного удаления
+func (r *UserRepository) UserExists(ctx context.Context, username string, email string) (bool, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 OR email = $2)`
 	var exists bool
-	err := r.readDB.QueryRow(query, username, email).Scan(&exists)
-	defer func() {
-		duration := time.Since(start)
-		monitoring.ObserveDatabaseQuery("user_exists", err == nil, duration)
-	}()
+	err := r.db.ReaderMaxStaleness(ctx, 0, authMaxStaleness).QueryRowContext(ctx, "user_exists", query, username, email).Scan(&exists)
 	return exists, err
 }
 
-func (r *UserRepository) SearchUsers(firstName, lastName string) ([]models.UserResponse, error) {
+func (r *UserRepository) SearchUsers(ctx context.Context, firstName, lastName string) ([]models.UserResponse, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpSearch)
+	defer cancel()
+
 	query := `
         SELECT 
             id, username, email, first_name, last_name, 
             birth_date, gender, interests, city, created_at
         FROM users 
-        WHERE first_name ILIKE $1 AND last_name ILIKE $2
+        WHERE first_name ILIKE $1 AND last_name ILIKE $2 AND deleted_at IS NULL
         ORDER BY id
         LIMIT 100
     `
@@ -228,7 +236,7 @@ func (r *UserRepository) SearchUsers(firstName, lastName string) ([]models.UserR
 	firstNamePattern := "%" + strings.ToLower(firstName) + "%"
 	lastNamePattern := "%" + strings.ToLower(lastName) + "%"
 
-	rows, err := r.readDB.Query(query, firstNamePattern, lastNamePattern)
+	rows, err := r.db.ReaderMaxStaleness(ctx, 0, searchMaxStaleness).QueryContext(ctx, "search_users", query, firstNamePattern, lastNamePattern)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
@@ -263,14 +271,17 @@ func (r *UserRepository) SearchUsers(firstName, lastName string) ([]models.UserR
 }
 
 // SearchUsersWithPaging поиск с пагинацией
-func (r *UserRepository) SearchUsersWithPaging(firstName string, lastName string, limit int, offset int) ([]models.UserResponse, int, error) {
+func (r *UserRepository) SearchUsersWithPaging(ctx context.Context, firstName string, lastName string, limit int, offset int) ([]models.UserResponse, int, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpSearch)
+	defer cancel()
+
 	// Запрос для получения пользователей
 	usersQuery := `
         SELECT 
             id, username, email, first_name, last_name, 
             birth_date, gender, interests, city, created_at
         FROM users 
-        WHERE first_name ILIKE $1 AND last_name ILIKE $2
+        WHERE first_name ILIKE $1 AND last_name ILIKE $2 AND deleted_at IS NULL
         ORDER BY id
         LIMIT $3 OFFSET $4
     `
@@ -289,21 +300,23 @@ func (r *UserRepository) SearchUsersWithPaging(firstName string, lastName string
 	countQuery := `
         SELECT COUNT(*) 
         FROM users 
-        WHERE first_name ILIKE $1 AND last_name ILIKE $2
+        WHERE first_name ILIKE $1 AND last_name ILIKE $2 AND deleted_at IS NULL
     `
 
 	firstNamePattern := "%" + strings.ToLower(firstName) + "%"
 	lastNamePattern := "%" + strings.ToLower(lastName) + "%"
 
+	readDB := r.db.ReaderMaxStaleness(ctx, 0, searchMaxStaleness)
+
 	// Выполняем запрос на подсчет
 	var total int
-	err := r.readDB.QueryRow(countQuery, firstNamePattern, lastNamePattern).Scan(&total)
+	err := readDB.QueryRowContext(ctx, "count_search_users", countQuery, firstNamePattern, lastNamePattern).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
 
 	// Выполняем запрос на получение данных
-	rows, err := r.readDB.Query(usersQuery, firstNamePattern, lastNamePattern, limit, offset)
+	rows, err := readDB.QueryContext(ctx, "search_users_paged", usersQuery, firstNamePattern, lastNamePattern, limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to search users: %w", err)
 	}
@@ -337,31 +350,143 @@ func (r *UserRepository) SearchUsersWithPaging(firstName string, lastName string
 	return users, total, nil
 }
 
-// func (r *UserRepository) UpdateUser(id int, updateReq *models.UpdateUserRequest) error {
-// 	query := `
-//         UPDATE users
-//         SET
-//             first_name = COALESCE($1, first_name),
-//             last_name = COALESCE($2, last_name),
-//             birth_date = COALESCE($3, birth_date),
-//             gender = COALESCE($4, gender),
-//             interests = COALESCE($5, interests),
-//             city = COALESCE($6, city),
-//             updated_at = $7
-//         WHERE id = $8
-//     `
-
-// 	_, err := r.writeDB.Exec(
-// 		query,
-// 		updateReq.FirstName,
-// 		updateReq.LastName,
-// 		updateReq.BirthDate,
-// 		updateReq.Gender,
-// 		updateReq.Interests,
-// 		updateReq.City,
-// 		time.Now(),
-// 		id,
-// 	)
-
-// 	return err
-// }
+// UpdateUser обновляет переданные поля профиля
+func (r *UserRepository) UpdateUser(ctx context.Context, id int, updateReq *models.UpdateUserRequest) error {
+	query := `
+        UPDATE users
+        SET
+            first_name = COALESCE($1, first_name),
+            last_name = COALESCE($2, last_name),
+            birth_date = COALESCE($3, birth_date),
+            gender = COALESCE($4, gender),
+            interests = COALESCE($5, interests),
+            city = COALESCE($6, city),
+            updated_at = $7
+        WHERE id = $8 AND deleted_at IS NULL
+    `
+
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	result, err := r.writeDB.ExecContext(
+		ctx,
+		"update_user",
+		query,
+		updateReq.FirstName,
+		updateReq.LastName,
+		updateReq.BirthDate,
+		updateReq.Gender,
+		updateReq.Interests,
+		updateReq.City,
+		time.Now(),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errUserNotFound
+	}
+
+	r.db.MarkWrite(ctx, id)
+	return nil
+}
+
+// IsActive проверяет, что аккаунт существует и не удален. Читается с реплики
+// с небольшим допустимым отставанием; после удаления своего аккаунта чтения
+// пользователя идут на primary.
+func (r *UserRepository) IsActive(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpRead)
+	defer cancel()
+
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
+	var active bool
+	err := r.db.ReaderMaxStaleness(ctx, id, authMaxStaleness).QueryRowContext(ctx, "user_is_active", query, id).Scan(&active)
+	return active, err
+}
+
+// DeleteUser помечает аккаунт удаленным. Пользователь и его данные скрываются из всех
+// запросов, но остаются в БД до окончательного удаления (PurgeDeletedUsers).
+func (r *UserRepository) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `UPDATE users SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := r.writeDB.ExecContext(ctx, "delete_user", query, id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errUserNotFound
+	}
+
+	r.db.MarkWrite(ctx, id)
+	return nil
+}
+
+// RestoreUser снимает пометку об удалении с аккаунта, удаленного после deletedAfter
+func (r *UserRepository) RestoreUser(ctx context.Context, id int, deletedAfter time.Time) error {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at > $2`
+
+	result, err := r.writeDB.ExecContext(ctx, "restore_user", query, id, deletedAfter)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return apperror.Conflict(apperror.CodeRestoreWindowExpired, "Account can no longer be restored")
+	}
+
+	r.db.MarkWrite(ctx, id)
+	return nil
+}
+
+// PurgeDeletedUsers окончательно удаляет до batchSize пользователей, удаленных раньше before,
+// вместе со всеми их данными (каскадно). Возвращает количество удаленных пользователей
+// и ключи файлов вложений их постов.
+func (r *UserRepository) PurgeDeletedUsers(ctx context.Context, before time.Time, batchSize int) (int, []string, error) {
+	ctx, cancel := r.db.WithTimeout(ctx, database.OpWrite)
+	defer cancel()
+
+	query := `
+        WITH purged AS (
+            DELETE FROM users
+            WHERE id IN (
+                SELECT id FROM users
+                WHERE deleted_at < $1
+                ORDER BY deleted_at
+                LIMIT $2
+            )
+            RETURNING id
+        )
+        SELECT u.id, a.blob_key, a.thumbnail_key
+        FROM purged u
+        LEFT JOIN post_attachments a ON a.user_id = u.id
+    `
+
+	rows, err := r.writeDB.QueryContext(ctx, "purge_deleted_users", query, before, batchSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	return scanPurged(rows)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// AccountStatusCacheTTL время, в течение которого результат проверки аккаунта
+// не перечитывается из БД. На других экземплярах API удаление аккаунта
+// начинает действовать не позже чем через этот интервал.
+const AccountStatusCacheTTL = 30 * time.Second
+
+type accountStatus struct {
+	active bool
+	until  time.Time
+}
+
+// accountStatusCache кэш активности аккаунтов в памяти процесса: проверка
+// выполняется на каждый авторизованный запрос
+type accountStatusCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	statuses  map[int]accountStatus
+	lastSweep time.Time
+}
+
+func newAccountStatusCache(ttl time.Duration) *accountStatusCache {
+	return &accountStatusCache{
+		ttl:       ttl,
+		statuses:  make(map[int]accountStatus),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get возвращает закэшированную активность аккаунта
+func (c *accountStatusCache) Get(userID int) (active, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status, ok := c.statuses[userID]
+	if !ok || time.Now().After(status.until) {
+		return false, false
+	}
+	return status.active, true
+}
+
+// Set запоминает активность аккаунта на время ttl
+func (c *accountStatusCache) Set(userID int, active bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.statuses[userID] = accountStatus{active: active, until: now.Add(c.ttl)}
+
+	// Периодически удаляем истекшие записи
+	if now.Sub(c.lastSweep) > c.ttl {
+		for id, status := range c.statuses {
+			if now.After(status.until) {
+				delete(c.statuses, id)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+// Invalidate удаляет запись: следующая проверка прочитает аккаунт из БД
+func (c *accountStatusCache) Invalidate(userID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.statuses, userID)
+}
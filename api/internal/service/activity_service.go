@@ -0,0 +1,134 @@
+package service
+
+import (
+	"api/internal/cache"
+	"api/internal/logging"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	activeUsersKey = "active_users"
+
+	// Не чаще одной записи в Redis на пользователя за этот интервал
+	activityTouchInterval = time.Minute
+)
+
+// ActivityService отслеживает недавно активных пользователей
+// (используется для прогрева кэша)
+type ActivityService struct {
+	cache  *cache.RedisCache
+	tasks  *BackgroundTasks
+	window time.Duration
+
+	mu sync.Mutex
+	// Время последней записи в Redis для пользователей, активных за последний activityTouchInterval
+	lastTouch map[int]time.Time
+}
+
+func NewActivityService(redisCache *cache.RedisCache, tasks *BackgroundTasks, window time.Duration) *ActivityService {
+	return &ActivityService{
+		cache:     redisCache,
+		tasks:     tasks,
+		window:    window,
+		lastTouch: make(map[int]time.Time),
+	}
+}
+
+// Touch отмечает активность пользователя
+func (s *ActivityService) Touch(ctx context.Context, userID int) {
+	if s.cache == nil {
+		return
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	if last, ok := s.lastTouch[userID]; ok && now.Sub(last) < activityTouchInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastTouch[userID] = now
+	s.mu.Unlock()
+
+	if err := s.cache.AddToSortedSet(ctx, activeUsersKey, strconv.Itoa(userID), float64(now.Unix())); err != nil {
+		logging.FromContext(ctx).Warn("Failed to track user activity", "user_id", userID, logging.Err(err))
+	}
+}
+
+// StartPruning запускает периодическое удаление устаревших записей об активности.
+// Без него в долго работающем процессе карта и множество растут с каждым новым пользователем.
+func (s *ActivityService) StartPruning(ctx context.Context) {
+	if s.cache == nil {
+		return
+	}
+	s.tasks.Go(ctx, s.runPruning)
+}
+
+func (s *ActivityService) runPruning(ctx context.Context) {
+	ticker := time.NewTicker(activityTouchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		}
+
+		now := time.Now()
+		s.trim(ctx, now.Add(-s.window))
+		s.pruneTouches(now.Add(-activityTouchInterval))
+	}
+}
+
+// GetActiveUsers возвращает пользователей, активных в пределах окна, начиная с самых недавних
+func (s *ActivityService) GetActiveUsers(ctx context.Context, limit int) ([]int, error) {
+	if s.cache == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	since := now.Add(-s.window)
+
+	s.trim(ctx, since)
+
+	members, err := s.cache.GetSortedSetByScore(ctx, activeUsersKey, float64(since.Unix()), float64(now.Unix()), int64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.Atoi(member)
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, nil
+}
+
+// trim удаляет из Redis записи об активности раньше since
+func (s *ActivityService) trim(ctx context.Context, since time.Time) {
+	if err := s.cache.TrimSortedSetByScore(ctx, activeUsersKey, float64(since.Unix())); err != nil {
+		logging.FromContext(ctx).Warn("Failed to trim active users", logging.Err(err))
+	}
+}
+
+// pruneTouches удаляет из карты отметки раньше since: после них Touch все равно пишет в Redis
+func (s *ActivityService) pruneTouches(since time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, last := range s.lastTouch {
+		if last.Before(since) {
+			delete(s.lastTouch, userID)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"api/internal/apperror"
+	"api/internal/config"
+	"api/internal/logging"
+	"api/internal/media"
+	"api/internal/models"
+	"api/internal/monitoring"
+	"api/internal/repository"
+	"api/internal/storage"
+	"api/internal/validation"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AttachmentService изображения, прикрепленные к постам.
+// Файл и его миниатюра сохраняются в хранилище вложений, в БД - их ключи.
+// Файлы удаляются в фоне после удаления записи: потерянный файл в хранилище
+// безопаснее, чем запись без файла.
+// Клиенты получают файлы через API по подписанным ссылкам, выданным конкретному читателю.
+type AttachmentService struct {
+	attachmentRepo *repository.AttachmentRepository
+	store          storage.BlobStore
+	signer         *media.URLSigner
+	tasks          *BackgroundTasks
+	cfg            config.MediaConfig
+}
+
+func NewAttachmentService(attachmentRepo *repository.AttachmentRepository, store storage.BlobStore, signer *media.URLSigner, tasks *BackgroundTasks, cfg config.MediaConfig) *AttachmentService {
+	return &AttachmentService{
+		attachmentRepo: attachmentRepo,
+		store:          store,
+		signer:         signer,
+		tasks:          tasks,
+		cfg:            cfg,
+	}
+}
+
+// Upload проверяет изображение, строит миниатюру и прикрепляет их к посту автора userID
+func (s *AttachmentService) Upload(ctx context.Context, postID, userID int, data []byte) (*models.Attachment, error) {
+	// Тип определяется по содержимому: имени файла и заголовку клиента не доверяем
+	contentType := media.DetectType(data)
+	if err := validation.Attachment(int64(len(data)), s.cfg.MaxSize, contentType); err != nil {
+		monitoring.RecordMediaUpload("rejected", 0)
+		return nil, err
+	}
+
+	// Предварительная проверка, чтобы не обрабатывать и не сохранять лишние файлы
+	if err := s.attachmentRepo.CheckAttachmentLimit(ctx, postID, userID, s.cfg.MaxPerPost); err != nil {
+		if errors.Is(err, apperror.ErrConflict) {
+			monitoring.RecordMediaUpload("rejected", 0)
+		}
+		return nil, err
+	}
+
+	start := time.Now()
+	img, err := media.Process(data, contentType, s.cfg.ThumbnailSize)
+	monitoring.RecordMediaProcessing(time.Since(start))
+	if err != nil {
+		monitoring.RecordMediaUpload("rejected", 0)
+		return nil, validation.Image(err)
+	}
+
+	name := uuid.NewString()
+	attachment := &models.Attachment{
+		PostID:       postID,
+		UserID:       userID,
+		BlobKey:      fmt.Sprintf("posts/%d/%s%s", postID, name, media.Extension(contentType)),
+		ThumbnailKey: fmt.Sprintf("posts/%d/%s_thumb%s", postID, name, media.Extension(img.ThumbnailType)),
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        img.Width,
+		Height:       img.Height,
+	}
+
+	if err := s.store.Put(ctx, attachment.BlobKey, bytes.NewReader(data), attachment.Size, contentType); err != nil {
+		monitoring.RecordMediaUpload("failed", 0)
+		return nil, fmt.Errorf("store attachment: %w", err)
+	}
+	if err := s.store.Put(ctx, attachment.ThumbnailKey, bytes.NewReader(img.Thumbnail), int64(len(img.Thumbnail)), img.ThumbnailType); err != nil {
+		monitoring.RecordMediaUpload("failed", 0)
+		s.DeleteBlobs(ctx, attachment.BlobKey)
+		return nil, fmt.Errorf("store thumbnail: %w", err)
+	}
+
+	if err := s.attachmentRepo.CreateAttachment(ctx, attachment, s.cfg.MaxPerPost); err != nil {
+		monitoring.RecordMediaUpload("failed", 0)
+		s.DeleteBlobs(ctx, attachment.BlobKey, attachment.ThumbnailKey)
+		return nil, err
+	}
+
+	monitoring.RecordMediaUpload("success", attachment.Size)
+	s.setURLs(userID, attachment)
+	return attachment, nil
+}
+
+// Fill заполняет вложения постов. В отличие от реакций, вложения входят
+// в закэшированные страницы, поэтому ошибка загрузки возвращается.
+// Ссылки на файлы подписываются для viewerID; при чтении из кэша их заменяет SetURLs.
+func (s *AttachmentService) Fill(ctx context.Context, viewerID int, posts []models.PostResponse) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]int, len(posts))
+	for i := range posts {
+		postIDs[i] = posts[i].ID
+	}
+
+	attachments, err := s.attachmentRepo.GetAttachments(ctx, viewerID, postIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Attachments = attachments[posts[i].ID]
+	}
+	s.SetURLs(viewerID, posts)
+	return nil
+}
+
+// SetURLs подписывает ссылки на файлы вложений постов для читателя viewerID
+func (s *AttachmentService) SetURLs(viewerID int, posts []models.PostResponse) {
+	for i := range posts {
+		for j := range posts[i].Attachments {
+			s.setURLs(viewerID, &posts[i].Attachments[j])
+		}
+	}
+}
+
+// Resolve проверяет подписанную ссылку и возвращает вложение и время, до которого ссылка действует.
+// Доступ читателя к посту проверяет вызывающий (PostService.OpenAttachment).
+func (s *AttachmentService) Resolve(ctx context.Context, link media.Link) (*models.Attachment, time.Time, error) {
+	expiresAt, err := s.signer.Verify(link)
+	if err != nil {
+		return nil, time.Time{}, apperror.Forbidden(apperror.CodeMediaURLInvalid, "Media URL is invalid or expired").Wrap(err)
+	}
+
+	attachment, err := s.attachmentRepo.GetAttachment(ctx, link.AttachmentID, link.ViewerID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return attachment, expiresAt, nil
+}
+
+// Open открывает файл или миниатюру вложения и возвращает тип содержимого
+func (s *AttachmentService) Open(ctx context.Context, attachment *models.Attachment, thumbnail bool) (io.ReadCloser, string, error) {
+	key := attachment.BlobKey
+	if thumbnail {
+		key = attachment.ThumbnailKey
+	}
+
+	body, contentType, err := s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, "", apperror.NotFound(apperror.CodeAttachmentNotFound, "Attachment not found").Wrap(err)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return body, contentType, nil
+}
+
+// Delete удаляет вложение по запросу автора поста и возвращает его
+func (s *AttachmentService) Delete(ctx context.Context, attachmentID, userID int) (*models.Attachment, error) {
+	attachment, err := s.attachmentRepo.DeleteAttachment(ctx, attachmentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.DeleteBlobs(ctx, attachment.BlobKey, attachment.ThumbnailKey)
+	return attachment, nil
+}
+
+// DeleteBlobs удаляет файлы из хранилища в фоне. Ошибки только логируются:
+// запись о вложении уже удалена или не создана.
+// Файлы вложений удаленных постов удаляются при окончательном удалении постов.
+func (s *AttachmentService) DeleteBlobs(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		for _, key := range keys {
+			if err := s.store.Delete(ctx, key); err != nil {
+				logging.FromContext(ctx).Warn("Failed to delete attachment blob", "key", key, logging.Err(err))
+			}
+		}
+	})
+}
+
+func (s *AttachmentService) setURLs(viewerID int, attachment *models.Attachment) {
+	attachment.URL = s.signer.URL(attachment.ID, false, viewerID)
+	attachment.ThumbnailURL = s.signer.URL(attachment.ID, true, viewerID)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"api/internal/config"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/monitoring"
+	"api/internal/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Действия, записываемые в журнал аудита
+const (
+	AuditUserRegister     = "user.register"
+	AuditUserLogin        = "user.login"
+	AuditUserLoginFailed  = "user.login_failed"
+	AuditProfileUpdate    = "user.profile_update"
+	AuditUserDelete       = "user.delete"
+	AuditUserRestore      = "user.restore"
+	AuditPostCreate       = "post.create"
+	AuditPostUpdate       = "post.update"
+	AuditPostDelete       = "post.delete"
+	AuditPostRestore      = "post.restore"
+	AuditCommentCreate    = "comment.create"
+	AuditCommentUpdate    = "comment.update"
+	AuditCommentDelete    = "comment.delete"
+	AuditAttachmentAdd    = "attachment.add"
+	AuditAttachmentDelete = "attachment.delete"
+	AuditFriendAdd        = "friend.add"
+	AuditFriendDelete     = "friend.delete"
+	AuditCacheInvalidate  = "cache.invalidate"
+)
+
+// Типы объектов действий
+const (
+	AuditTargetUser       = "user"
+	AuditTargetPost       = "post"
+	AuditTargetComment    = "comment"
+	AuditTargetAttachment = "attachment"
+)
+
+// auditPruneBatchSize количество записей, удаляемых одним запросом при очистке
+const auditPruneBatchSize = 5000
+
+type clientInfoKey struct{}
+
+// clientInfo адрес и User-Agent клиента, выполнившего запрос
+type clientInfo struct {
+	ip        string
+	userAgent string
+}
+
+// WithClientInfo сохраняет в контексте адрес и User-Agent клиента для журнала аудита
+func WithClientInfo(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, clientInfo{ip: ip, userAgent: userAgent})
+}
+
+// AuditService ведет журнал аудита действий, важных для безопасности
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+	tasks     *BackgroundTasks
+	cfg       config.AuditConfig
+}
+
+func NewAuditService(auditRepo *repository.AuditRepository, tasks *BackgroundTasks, cfg config.AuditConfig) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		tasks:     tasks,
+		cfg:       cfg,
+	}
+}
+
+// Record записывает действие actorID (0 - неизвестный пользователь) над объектом
+// targetType/targetID (пустой тип - без объекта). Адрес и User-Agent берутся из контекста запроса.
+// Ошибка записи не прерывает действие, а только пишется в лог.
+func (s *AuditService) Record(ctx context.Context, action string, actorID int, targetType string, targetID int, details map[string]interface{}) {
+	entry := &models.AuditEntry{
+		Action:    action,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if actorID != 0 {
+		entry.ActorID = &actorID
+	}
+	if targetType != "" {
+		entry.TargetType = targetType
+		entry.TargetID = &targetID
+	}
+	if info, ok := ctx.Value(clientInfoKey{}).(clientInfo); ok {
+		entry.IP = info.ip
+		entry.UserAgent = info.userAgent
+	}
+
+	// Действие уже выполнено, поэтому запись не отменяется вместе с запросом
+	err := s.auditRepo.CreateEntry(context.WithoutCancel(ctx), entry)
+	monitoring.RecordAuditWrite(action, err == nil)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to write audit log",
+			"action", action,
+			"actor_id", actorID,
+			"target_type", targetType,
+			"target_id", targetID,
+			logging.Err(err),
+		)
+	}
+}
+
+// GetEntries возвращает страницу журнала аудита по фильтрам
+func (s *AuditService) GetEntries(ctx context.Context, filter *models.AuditFilter, page, pageSize int) (*models.AuditLogResponse, error) {
+	page, pageSize = normalizePaging(page, pageSize)
+
+	entries, err := s.auditRepo.FindEntries(ctx, filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuditLogResponse{
+		Entries:  entries,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// StartPruning запускает периодическое удаление записей старше срока хранения.
+// При нулевом сроке хранения или интервале записи не удаляются.
+func (s *AuditService) StartPruning(ctx context.Context) {
+	if s.cfg.Retention <= 0 || s.cfg.PruneInterval <= 0 {
+		return
+	}
+	s.tasks.Go(ctx, s.runPruning)
+}
+
+func (s *AuditService) runPruning(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Prune(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		}
+	}
+}
+
+// Prune удаляет записи старше срока хранения порциями, пока они не закончатся
+func (s *AuditService) Prune(ctx context.Context) {
+	before := time.Now().Add(-s.cfg.Retention)
+
+	var total int64
+	for {
+		deleted, err := s.auditRepo.DeleteEntriesBefore(ctx, before, auditPruneBatchSize)
+		total += deleted
+		monitoring.RecordAuditPruned(deleted)
+		if err != nil {
+			slog.Warn("Failed to prune audit log", "deleted", total, "error", err)
+			return
+		}
+		if deleted < auditPruneBatchSize {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		default:
+		}
+	}
+
+	if total > 0 {
+		slog.Info("Audit log pruned", "deleted", total, "before", before)
+	}
+}
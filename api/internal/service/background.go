@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// BackgroundTasks запускает фоновые задачи сервисов (инвалидация и запись кэша, прогрев)
+// и позволяет дождаться их завершения при остановке сервиса
+type BackgroundTasks struct {
+	// Контекст отменяется, если задачи не успели завершиться за время остановки
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	stopped  bool
+	stopping chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewBackgroundTasks() *BackgroundTasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BackgroundTasks{
+		ctx:      ctx,
+		cancel:   cancel,
+		stopping: make(chan struct{}),
+	}
+}
+
+// Go запускает задачу в фоне. Контекст задачи сохраняет значения ctx (логгер, трассу),
+// но не отменяется вместе с запросом: задача прерывается, только если не успела
+// завершиться за время остановки. После начала остановки задача выполняется синхронно.
+func (b *BackgroundTasks) Go(ctx context.Context, task func(ctx context.Context)) {
+	taskCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(b.ctx, cancel)
+
+	run := func() {
+		defer cancel()
+		defer stop()
+		task(taskCtx)
+	}
+
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		run()
+		return
+	}
+	b.wg.Add(1)
+	b.mu.Unlock()
+
+	go func() {
+		defer b.wg.Done()
+		run()
+	}()
+}
+
+// Stopping закрывается в начале остановки, чтобы длительные задачи (прогрев кэша)
+// не брали новую работу
+func (b *BackgroundTasks) Stopping() <-chan struct{} {
+	return b.stopping
+}
+
+// Shutdown ждет завершения запущенных задач.
+// Если ctx истекает раньше, оставшиеся задачи отменяются и возвращается ошибка ctx.
+func (b *BackgroundTasks) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.stopped {
+		b.stopped = true
+		close(b.stopping)
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"api/internal/monitoring"
+	"api/internal/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// BusinessMetricsService периодически обновляет бизнес-метрики
+// (количество пользователей, постов и дружб) по данным реплики
+type BusinessMetricsService struct {
+	statsRepo *repository.StatsRepository
+	tasks     *BackgroundTasks
+	interval  time.Duration
+}
+
+func NewBusinessMetricsService(statsRepo *repository.StatsRepository, tasks *BackgroundTasks, interval time.Duration) *BusinessMetricsService {
+	return &BusinessMetricsService{
+		statsRepo: statsRepo,
+		tasks:     tasks,
+		interval:  interval,
+	}
+}
+
+// Start запускает обновление метрик в фоне до остановки сервиса.
+// При нулевом интервале метрики не обновляются.
+func (s *BusinessMetricsService) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	s.tasks.Go(ctx, s.run)
+}
+
+func (s *BusinessMetricsService) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Refresh(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		}
+	}
+}
+
+// Refresh перечитывает агрегаты из БД и обновляет метрики
+func (s *BusinessMetricsService) Refresh(ctx context.Context) {
+	totals, err := s.statsRepo.GetTotals(ctx)
+	if err != nil {
+		slog.Warn("Failed to refresh business metrics", "error", err)
+		return
+	}
+
+	monitoring.SetBusinessTotals(totals.Users, totals.Posts, totals.Friendships)
+}
@@ -3,17 +3,20 @@ package service
 import (
 	"api/internal/cache"
 	"api/internal/models"
+	"context"
 	"fmt"
 	"time"
 )
 
 type CacheService struct {
-	cache *cache.RedisCache
+	cache        *cache.RedisCache
+	auditService *AuditService
 }
 
-func NewCacheService(redisCache *cache.RedisCache) *CacheService {
+func NewCacheService(redisCache *cache.RedisCache, auditService *AuditService) *CacheService {
 	return &CacheService{
-		cache: redisCache,
+		cache:        redisCache,
+		auditService: auditService,
 	}
 }
 
@@ -30,17 +33,18 @@ func (s *CacheService) GenerateFeedCacheKey(userID, page, pageSize int) string {
 	return fmt.Sprintf("feed:user:%d:page:%d:size:%d", userID, page, pageSize)
 }
 
-// GenerateUserPostsCacheKey генерирует ключ для кэша постов пользователя
-func (s *CacheService) GenerateUserPostsCacheKey(userID, page, pageSize int) string {
-	return fmt.Sprintf("posts:user:%d:page:%d:size:%d", userID, page, pageSize)
+// GenerateUserPostsCacheKey генерирует ключ для кэша постов пользователя.
+// audience - кому видна страница (автору, друзьям, всем): у каждой аудитории свой набор постов.
+func (s *CacheService) GenerateUserPostsCacheKey(userID int, audience string, page, pageSize int) string {
+	return fmt.Sprintf("posts:user:%d:%s:page:%d:size:%d", userID, audience, page, pageSize)
 }
 
 // GetFeedFromCache получает ленту из кэша
-func (s *CacheService) GetFeedFromCache(userID, page, pageSize int) (*models.FeedResponse, error) {
+func (s *CacheService) GetFeedFromCache(ctx context.Context, userID, page, pageSize int) (*models.FeedResponse, error) {
 	key := s.GenerateFeedCacheKey(userID, page, pageSize)
 
 	var feed models.FeedResponse
-	if err := s.cache.Get(key, &feed); err != nil {
+	if err := s.cache.Get(ctx, key, &feed); err != nil {
 		return nil, err
 	}
 
@@ -48,17 +52,17 @@ func (s *CacheService) GetFeedFromCache(userID, page, pageSize int) (*models.Fee
 }
 
 // SetFeedToCache сохраняет ленту в кэш
-func (s *CacheService) SetFeedToCache(userID, page, pageSize int, feed *models.FeedResponse) error {
+func (s *CacheService) SetFeedToCache(ctx context.Context, userID, page, pageSize int, feed *models.FeedResponse) error {
 	key := s.GenerateFeedCacheKey(userID, page, pageSize)
-	return s.cache.Set(key, feed, FeedCacheTTL)
+	return s.cache.Set(ctx, key, feed, FeedCacheTTL)
 }
 
 // GetUserPostsFromCache получает посты пользователя из кэша
-func (s *CacheService) GetUserPostsFromCache(userID, page, pageSize int) (*models.FeedResponse, error) {
-	key := s.GenerateUserPostsCacheKey(userID, page, pageSize)
+func (s *CacheService) GetUserPostsFromCache(ctx context.Context, userID int, audience string, page, pageSize int) (*models.FeedResponse, error) {
+	key := s.GenerateUserPostsCacheKey(userID, audience, page, pageSize)
 
 	var feed models.FeedResponse
-	if err := s.cache.Get(key, &feed); err != nil {
+	if err := s.cache.Get(ctx, key, &feed); err != nil {
 		return nil, err
 	}
 
@@ -66,46 +70,46 @@ func (s *CacheService) GetUserPostsFromCache(userID, page, pageSize int) (*model
 }
 
 // SetUserPostsToCache сохраняет посты пользователя в кэш
-func (s *CacheService) SetUserPostsToCache(userID, page, pageSize int, feed *models.FeedResponse) error {
-	key := s.GenerateUserPostsCacheKey(userID, page, pageSize)
-	return s.cache.Set(key, feed, UserPostsCacheTTL)
+func (s *CacheService) SetUserPostsToCache(ctx context.Context, userID int, audience string, page, pageSize int, feed *models.FeedResponse) error {
+	key := s.GenerateUserPostsCacheKey(userID, audience, page, pageSize)
+	return s.cache.Set(ctx, key, feed, UserPostsCacheTTL)
 }
 
 // InvalidateUserFeedCache инвалидирует кэш ленты пользователя
-func (s *CacheService) InvalidateUserFeedCache(userID int) error {
+func (s *CacheService) InvalidateUserFeedCache(ctx context.Context, userID int) error {
 	pattern := fmt.Sprintf("feed:user:%d:*", userID)
 
 	// Используем безопасное удаление для кластера
 	if s.cache.GetClientType() == "cluster" {
-		return s.cache.SafeDeleteByPattern(pattern)
+		return s.cache.SafeDeleteByPattern(ctx, pattern)
 	}
-	return s.cache.DeleteByPattern(pattern)
+	return s.cache.DeleteByPattern(ctx, pattern)
 }
 
 // InvalidateUserPostsCache инвалидирует кэш постов пользователя
-func (s *CacheService) InvalidateUserPostsCache(userID int) error {
+func (s *CacheService) InvalidateUserPostsCache(ctx context.Context, userID int) error {
 	pattern := fmt.Sprintf("posts:user:%d:*", userID)
 
 	if s.cache.GetClientType() == "cluster" {
-		return s.cache.SafeDeleteByPattern(pattern)
+		return s.cache.SafeDeleteByPattern(ctx, pattern)
 	}
-	return s.cache.DeleteByPattern(pattern)
+	return s.cache.DeleteByPattern(ctx, pattern)
 }
 
 // InvalidateUserCache инвалидирует кэш данных пользователя
-func (s *CacheService) InvalidateUserCache(userID int) error {
+func (s *CacheService) InvalidateUserCache(ctx context.Context, userID int) error {
 	key := fmt.Sprintf("user:%d", userID)
-	return s.cache.Delete(key)
+	return s.cache.Delete(ctx, key)
 }
 
 // InvalidateSearchCache инвалидирует кэш поиска
-func (s *CacheService) InvalidateSearchCache(firstName, lastName string) error {
+func (s *CacheService) InvalidateSearchCache(ctx context.Context, firstName, lastName string) error {
 	pattern := fmt.Sprintf("search:%s:%s:*", firstName, lastName)
 
 	if s.cache.GetClientType() == "cluster" {
-		return s.cache.SafeDeleteByPattern(pattern)
+		return s.cache.SafeDeleteByPattern(ctx, pattern)
 	}
-	return s.cache.DeleteByPattern(pattern)
+	return s.cache.DeleteByPattern(ctx, pattern)
 }
 
 // GetCacheStats возвращает статистику кэша
@@ -116,23 +120,23 @@ func (s *CacheService) GetCacheStats() (map[string]interface{}, error) {
 }
 
 // HealthCheck проверяет состояние кэша
-func (s *CacheService) HealthCheck() error {
-	return s.cache.HealthCheck()
+func (s *CacheService) HealthCheck(ctx context.Context) error {
+	return s.cache.HealthCheck(ctx)
 }
 
-// RefreshCache принудительно обновляет кэш пользователя
-func (s *CacheService) RefreshCache(userID int) error {
+// RefreshCache принудительно обновляет кэш пользователя userID по запросу actorID
+func (s *CacheService) RefreshCache(ctx context.Context, actorID, userID int) error {
 	errors := []error{}
 
-	if err := s.InvalidateUserFeedCache(userID); err != nil {
+	if err := s.InvalidateUserFeedCache(ctx, userID); err != nil {
 		errors = append(errors, fmt.Errorf("feed cache: %w", err))
 	}
 
-	if err := s.InvalidateUserPostsCache(userID); err != nil {
+	if err := s.InvalidateUserPostsCache(ctx, userID); err != nil {
 		errors = append(errors, fmt.Errorf("posts cache: %w", err))
 	}
 
-	if err := s.InvalidateUserCache(userID); err != nil {
+	if err := s.InvalidateUserCache(ctx, userID); err != nil {
 		errors = append(errors, fmt.Errorf("user cache: %w", err))
 	}
 
@@ -140,5 +144,6 @@ func (s *CacheService) RefreshCache(userID int) error {
 		return fmt.Errorf("cache refresh errors: %v", errors)
 	}
 
+	s.auditService.Record(ctx, AuditCacheInvalidate, actorID, AuditTargetUser, userID, nil)
 	return nil
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"api/internal/cache"
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -17,15 +18,15 @@ func NewCacheVersionService(redisCache *cache.RedisCache) *CacheVersionService {
 }
 
 // GetCacheVersion получает текущую версию кэша для пользователя
-func (c *CacheVersionService) GetCacheVersion(userID int) (int, error) {
+func (c *CacheVersionService) GetCacheVersion(ctx context.Context, userID int) (int, error) {
 	key := fmt.Sprintf("cache_version:user:%d", userID)
 
 	// Используем существующие методы RedisCache вместо прямого доступа
 	var versionStr string
-	if err := c.cache.Get(key, &versionStr); err != nil {
+	if err := c.cache.Get(ctx, key, &versionStr); err != nil {
 		if err.Error() == "cache miss" {
 			// Если версии нет, создаем начальную
-			return c.SetCacheVersion(userID, 1)
+			return c.SetCacheVersion(ctx, userID, 1)
 		}
 		return 0, err
 	}
@@ -39,29 +40,29 @@ func (c *CacheVersionService) GetCacheVersion(userID int) (int, error) {
 }
 
 // SetCacheVersion устанавливает новую версию кэша
-func (c *CacheVersionService) SetCacheVersion(userID, version int) (int, error) {
+func (c *CacheVersionService) SetCacheVersion(ctx context.Context, userID, version int) (int, error) {
 	key := fmt.Sprintf("cache_version:user:%d", userID)
-	err := c.cache.Set(key, strconv.Itoa(version), 0) // Бессрочное хранение
+	err := c.cache.Set(ctx, key, strconv.Itoa(version), 0) // Бессрочное хранение
 	return version, err
 }
 
 // IncrementCacheVersion увеличивает версию кэша
-func (c *CacheVersionService) IncrementCacheVersion(userID int) (int, error) {
+func (c *CacheVersionService) IncrementCacheVersion(ctx context.Context, userID int) (int, error) {
 	// Получаем текущую версию
-	currentVersion, err := c.GetCacheVersion(userID)
+	currentVersion, err := c.GetCacheVersion(ctx, userID)
 	if err != nil {
 		// Если ошибка, создаем новую версию
-		return c.SetCacheVersion(userID, 1)
+		return c.SetCacheVersion(ctx, userID, 1)
 	}
 
 	// Увеличиваем версию
 	newVersion := currentVersion + 1
-	return c.SetCacheVersion(userID, newVersion)
+	return c.SetCacheVersion(ctx, userID, newVersion)
 }
 
 // GenerateVersionedKey генерирует ключ с версией
-func (c *CacheVersionService) GenerateVersionedKey(baseKey string, userID int) (string, error) {
-	version, err := c.GetCacheVersion(userID)
+func (c *CacheVersionService) GenerateVersionedKey(ctx context.Context, baseKey string, userID int) (string, error) {
+	version, err := c.GetCacheVersion(ctx, userID)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"api/internal/config"
+	"api/internal/logging"
+	"api/internal/monitoring"
+	"api/internal/tracing"
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Источники запуска прогрева кэша
+const (
+	WarmupTriggerStartup    = "startup"
+	WarmupTriggerInvalidate = "invalidate"
+)
+
+// CacheWarmupService заранее заполняет кэш лент и постов активных пользователей
+type CacheWarmupService struct {
+	postService     *PostService
+	activityService *ActivityService
+	tasks           *BackgroundTasks
+	cfg             config.CacheWarmupConfig
+}
+
+func NewCacheWarmupService(postService *PostService, activityService *ActivityService, tasks *BackgroundTasks, cfg config.CacheWarmupConfig) *CacheWarmupService {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Pages < 1 {
+		cfg.Pages = 1
+	}
+
+	return &CacheWarmupService{
+		postService:     postService,
+		activityService: activityService,
+		tasks:           tasks,
+		cfg:             cfg,
+	}
+}
+
+// WarmUpActiveUsers прогревает кэш всех недавно активных пользователей
+func (s *CacheWarmupService) WarmUpActiveUsers(ctx context.Context, trigger string) {
+	userIDs, err := s.activityService.GetActiveUsers(ctx, s.cfg.MaxUsers)
+	if err != nil {
+		logging.FromContext(ctx).Error("Cache warm-up: failed to get active users", "trigger", trigger, logging.Err(err))
+		return
+	}
+
+	s.WarmUpUsers(ctx, trigger, userIDs...)
+}
+
+// StartWarmUpActiveUsers запускает прогрев активных пользователей в фоне
+func (s *CacheWarmupService) StartWarmUpActiveUsers(ctx context.Context, trigger string) {
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		s.WarmUpActiveUsers(ctx, trigger)
+	})
+}
+
+// StartWarmUpUsers запускает прогрев указанных пользователей в фоне
+func (s *CacheWarmupService) StartWarmUpUsers(ctx context.Context, trigger string, userIDs ...int) {
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		s.WarmUpUsers(ctx, trigger, userIDs...)
+	})
+}
+
+// WarmUpUsers прогревает кэш указанных пользователей с ограниченной параллельностью
+func (s *CacheWarmupService) WarmUpUsers(ctx context.Context, trigger string, userIDs ...int) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	// Прогрев - отдельная трасса, в которую попадают запросы к БД и Redis
+	ctx, span := tracing.Tracer().Start(ctx, "cache.warmup", trace.WithAttributes(
+		attribute.String("warmup.trigger", trigger),
+		attribute.Int("warmup.users", len(userIDs)),
+	))
+	defer span.End()
+
+	logger := logging.FromContext(ctx).With("trigger", trigger)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		logger = logger.With("trace_id", traceID)
+	}
+	ctx = logging.WithContext(ctx, logger)
+
+	start := time.Now()
+	logger.Info("Cache warm-up started", "users", len(userIDs))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < s.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for userID := range jobs {
+				s.warmUpUser(ctx, userID)
+			}
+		}()
+	}
+
+	// При остановке сервиса новые пользователи не прогреваются
+	warmed := 0
+dispatch:
+	for _, userID := range userIDs {
+		select {
+		case jobs <- userID:
+			warmed++
+		case <-ctx.Done():
+			break dispatch
+		case <-s.tasks.Stopping():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	duration := time.Since(start)
+	monitoring.ObserveCacheWarmup(trigger, duration)
+	if warmed < len(userIDs) {
+		logger.Warn("Cache warm-up interrupted", "users", warmed, "total", len(userIDs), "duration_ms", duration.Milliseconds())
+		return
+	}
+	logger.Info("Cache warm-up finished", "users", len(userIDs), "duration_ms", duration.Milliseconds())
+}
+
+// warmUpUser прогревает первые страницы ленты и постов пользователя
+func (s *CacheWarmupService) warmUpUser(ctx context.Context, userID int) {
+	for page := 1; page <= s.cfg.Pages; page++ {
+		err := s.postService.RefreshFriendsPostsCache(ctx, userID, page, s.cfg.PageSize)
+		monitoring.RecordCacheWarmupEntry("feed", err == nil)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Cache warm-up: failed to warm feed", "user_id", userID, "page", page, logging.Err(err))
+		}
+
+		err = s.postService.RefreshUserPostsCache(ctx, userID, page, s.cfg.PageSize)
+		monitoring.RecordCacheWarmupEntry("user_posts", err == nil)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Cache warm-up: failed to warm posts", "user_id", userID, "page", page, logging.Err(err))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"api/internal/apperror"
+	"api/internal/models"
+	"api/internal/repository"
+	"context"
+	"encoding/base64"
+	"strconv"
+)
+
+type CommentService struct {
+	commentRepo  *repository.CommentRepository
+	postService  *PostService
+	auditService *AuditService
+}
+
+func NewCommentService(commentRepo *repository.CommentRepository, postService *PostService, auditService *AuditService) *CommentService {
+	return &CommentService{
+		commentRepo:  commentRepo,
+		postService:  postService,
+		auditService: auditService,
+	}
+}
+
+// CreateComment добавляет комментарий к видимому пользователю посту или ответ на комментарий
+func (s *CommentService) CreateComment(ctx context.Context, postID, userID int, req *models.CreateCommentRequest) (*models.Comment, error) {
+	if err := s.postService.CheckVisible(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{
+		PostID:   postID,
+		UserID:   userID,
+		ParentID: req.ParentID,
+		Content:  req.Content,
+	}
+
+	postAuthorID, err := s.commentRepo.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, AuditCommentCreate, userID, AuditTargetComment, comment.ID, map[string]interface{}{"post_id": postID})
+	s.postService.InvalidateAuthorPostsCaches(ctx, postAuthorID)
+
+	return comment, nil
+}
+
+// GetComments возвращает страницу комментариев поста или ответов на комментарий parentID.
+// cursor - значение NextCursor предыдущей страницы, пустой для первой.
+func (s *CommentService) GetComments(ctx context.Context, viewerID, postID int, parentID *int, cursor string, limit int) (*models.CommentsPage, error) {
+	afterID, err := decodeCommentCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.postService.CheckVisible(ctx, postID, viewerID); err != nil {
+		return nil, err
+	}
+	_, limit = normalizePaging(1, limit)
+
+	// Лишний комментарий показывает, есть ли следующая страница
+	comments, err := s.commentRepo.GetComments(ctx, viewerID, postID, parentID, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.CommentsPage{Comments: comments}
+	if len(comments) > limit {
+		page.Comments = comments[:limit]
+		page.NextCursor = encodeCommentCursor(page.Comments[limit-1].ID)
+	}
+	return page, nil
+}
+
+// UpdateComment изменяет текст комментария автором
+func (s *CommentService) UpdateComment(ctx context.Context, commentID, userID int, req *models.UpdateCommentRequest) error {
+	if err := s.commentRepo.UpdateComment(ctx, commentID, userID, req); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditCommentUpdate, userID, AuditTargetComment, commentID, nil)
+	return nil
+}
+
+// DeleteComment удаляет комментарий с ответами по запросу автора комментария или автора поста
+func (s *CommentService) DeleteComment(ctx context.Context, commentID, userID int) error {
+	postID, postAuthorID, err := s.commentRepo.DeleteComment(ctx, commentID, userID)
+	if err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditCommentDelete, userID, AuditTargetComment, commentID, map[string]interface{}{"post_id": postID})
+	s.postService.InvalidateAuthorPostsCaches(ctx, postAuthorID)
+
+	return nil
+}
+
+// Курсор - ID последнего комментария страницы. Кодируется, чтобы клиенты
+// не опирались на его содержимое.
+func encodeCommentCursor(commentID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(commentID)))
+}
+
+func decodeCommentCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if commentID, err := strconv.Atoi(string(raw)); err == nil && commentID > 0 {
+			return commentID, nil
+		}
+	}
+	return 0, apperror.BadRequest(apperror.CodeInvalidCursor, "Invalid cursor")
+}
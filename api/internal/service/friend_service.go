@@ -1,43 +1,76 @@
 package service
 
 import (
+	"api/internal/logging"
 	"api/internal/models"
 	"api/internal/repository"
+	"context"
 )
 
 type FriendService struct {
-	friendRepo *repository.FriendRepository
-	userRepo   *repository.UserRepository
+	friendRepo   *repository.FriendRepository
+	userRepo     *repository.UserRepository
+	cacheService *CacheService
+	auditService *AuditService
+	tasks        *BackgroundTasks
 }
 
-func NewFriendService(friendRepo *repository.FriendRepository, userRepo *repository.UserRepository) *FriendService {
+func NewFriendService(friendRepo *repository.FriendRepository, userRepo *repository.UserRepository, cacheService *CacheService, auditService *AuditService, tasks *BackgroundTasks) *FriendService {
 	return &FriendService{
-		friendRepo: friendRepo,
-		userRepo:   userRepo,
+		friendRepo:   friendRepo,
+		userRepo:     userRepo,
+		cacheService: cacheService,
+		auditService: auditService,
+		tasks:        tasks,
 	}
 }
 
 // AddFriend добавляет друга
-func (s *FriendService) AddFriend(userID, friendID int) error {
-	return s.friendRepo.AddFriend(userID, friendID)
+func (s *FriendService) AddFriend(ctx context.Context, userID, friendID int) error {
+	if err := s.friendRepo.AddFriend(ctx, userID, friendID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditFriendAdd, userID, AuditTargetUser, friendID, nil)
+	return nil
 }
 
 // DeleteFriend удаляет друга
-func (s *FriendService) DeleteFriend(userID, friendID int) error {
-	return s.friendRepo.DeleteFriend(userID, friendID)
+func (s *FriendService) DeleteFriend(ctx context.Context, userID, friendID int) error {
+	if err := s.friendRepo.DeleteFriend(ctx, userID, friendID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditFriendDelete, userID, AuditTargetUser, friendID, nil)
+
+	// В закэшированных лентах могут остаться посты бывшего друга, видимые только друзьям
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		for _, id := range []int{userID, friendID} {
+			if err := s.cacheService.InvalidateUserFeedCache(ctx, id); err != nil {
+				logging.FromContext(ctx).Warn("Failed to invalidate feed cache", "user_id", id, logging.Err(err))
+			}
+		}
+	})
+
+	return nil
 }
 
 // GetFriends возвращает список друзей
-func (s *FriendService) GetFriends(userID int) ([]models.FriendResponse, error) {
-	return s.friendRepo.GetFriends(userID)
+func (s *FriendService) GetFriends(ctx context.Context, userID int) ([]models.FriendResponse, error) {
+	return s.friendRepo.GetFriends(ctx, userID)
+}
+
+// GetFriendIDs возвращает ID друзей пользователя
+func (s *FriendService) GetFriendIDs(ctx context.Context, userID int) ([]int, error) {
+	return s.friendRepo.GetFriendIDs(ctx, userID)
 }
 
 // GetFriendshipStatus возвращает статус дружбы
-func (s *FriendService) GetFriendshipStatus(userID, friendID int) (*models.FriendshipStatus, error) {
-	return s.friendRepo.GetFriendshipStatus(userID, friendID)
+func (s *FriendService) GetFriendshipStatus(ctx context.Context, userID, friendID int) (*models.FriendshipStatus, error) {
+	return s.friendRepo.GetFriendshipStatus(ctx, userID, friendID)
 }
 
 // IsFriend проверяет, являются ли пользователи друзьями
-func (s *FriendService) IsFriend(userID, friendID int) (bool, error) {
-	return s.friendRepo.IsFriend(userID, friendID)
+func (s *FriendService) IsFriend(ctx context.Context, userID, friendID int) (bool, error) {
+	return s.friendRepo.IsFriend(ctx, userID, friendID)
 }
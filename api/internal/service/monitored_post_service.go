@@ -1,34 +1,37 @@
 package service
 
 import (
+	"api/internal/logging"
 	"api/internal/models"
 	"api/internal/monitoring"
 	"api/internal/repository"
-	"log"
+	"context"
 )
 
 type MonitoredPostService struct {
 	postRepo     *repository.PostRepository
 	cacheService *CacheService
+	tasks        *BackgroundTasks
 }
 
-func NewMonitoredPostService(postRepo *repository.PostRepository, cacheService *CacheService) *MonitoredPostService {
+func NewMonitoredPostService(postRepo *repository.PostRepository, cacheService *CacheService, tasks *BackgroundTasks) *MonitoredPostService {
 	return &MonitoredPostService{
 		postRepo:     postRepo,
 		cacheService: cacheService,
+		tasks:        tasks,
 	}
 }
 
 // GetFriendsPosts возвращает ленту постов друзей с кэшированием и метриками
-func (m *MonitoredPostService) GetFriendsPosts(userID, page, pageSize int) (*models.FeedResponse, error) {
+func (m *MonitoredPostService) GetFriendsPosts(ctx context.Context, userID, page, pageSize int) (*models.FeedResponse, error) {
 	// Пытаемся получить из кэша
-	if cached, err := m.cacheService.GetFeedFromCache(userID, page, pageSize); err == nil {
-		log.Printf("Cache HIT for feed: user=%d, page=%d", userID, page)
+	if cached, err := m.cacheService.GetFeedFromCache(ctx, userID, page, pageSize); err == nil {
+		logging.FromContext(ctx).Debug("Cache hit", "cache", "feed", "user_id", userID, "page", page)
 		monitoring.RecordCacheHit("feed")
 		return cached, nil
 	}
 
-	log.Printf("Cache MISS for feed: user=%d, page=%d", userID, page)
+	logging.FromContext(ctx).Debug("Cache miss", "cache", "feed", "user_id", userID, "page", page)
 	monitoring.RecordCacheMiss("feed")
 
 	if page < 1 {
@@ -39,7 +42,7 @@ func (m *MonitoredPostService) GetFriendsPosts(userID, page, pageSize int) (*mod
 	}
 
 	offset := (page - 1) * pageSize
-	posts, total, err := m.postRepo.GetFriendsPosts(userID, pageSize, offset)
+	posts, total, err := m.postRepo.GetFriendsPosts(ctx, userID, pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -54,42 +57,42 @@ func (m *MonitoredPostService) GetFriendsPosts(userID, page, pageSize int) (*mod
 	}
 
 	// Сохраняем в кэш асинхронно
-	go func() {
-		if err := m.cacheService.SetFeedToCache(userID, page, pageSize, feed); err != nil {
-			log.Printf("Failed to cache feed for user %d: %v", userID, err)
+	m.tasks.Go(ctx, func(ctx context.Context) {
+		if err := m.cacheService.SetFeedToCache(ctx, userID, page, pageSize, feed); err != nil {
+			logging.FromContext(ctx).Warn("Failed to cache feed", "user_id", userID, logging.Err(err))
 		}
-	}()
+	})
 
 	return feed, nil
 }
 
 // CreatePost создает пост с инвалидацией кэша и метриками
-func (m *MonitoredPostService) CreatePost(userID int, req *models.CreatePostRequest) (*models.Post, error) {
+func (m *MonitoredPostService) CreatePost(ctx context.Context, userID int, req *models.CreatePostRequest) (*models.Post, error) {
 	post := &models.Post{
 		UserID:  userID,
 		Title:   req.Title,
 		Content: req.Content,
 	}
 
-	err := m.postRepo.CreatePost(post)
+	err := m.postRepo.CreatePost(ctx, post)
 	if err != nil {
 		return nil, err
 	}
 
 	// Инвалидируем кэш и записываем метрики
-	go func() {
-		if err := m.cacheService.InvalidateUserFeedCache(userID); err != nil {
-			log.Printf("Failed to invalidate feed cache for user %d: %v", userID, err)
+	m.tasks.Go(ctx, func(ctx context.Context) {
+		if err := m.cacheService.InvalidateUserFeedCache(ctx, userID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to invalidate feed cache", "user_id", userID, logging.Err(err))
 		} else {
 			monitoring.RecordCacheInvalidation("feed")
 		}
 
-		if err := m.cacheService.InvalidateUserPostsCache(userID); err != nil {
-			log.Printf("Failed to invalidate posts cache for user %d: %v", userID, err)
+		if err := m.cacheService.InvalidateUserPostsCache(ctx, userID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to invalidate posts cache", "user_id", userID, logging.Err(err))
 		} else {
 			monitoring.RecordCacheInvalidation("user_posts")
 		}
-	}()
+	})
 
 	return post, nil
 }
@@ -1,174 +1,438 @@
 package service
 
 import (
+	"api/internal/apperror"
+	"api/internal/config"
+	"api/internal/logging"
+	"api/internal/media"
 	"api/internal/models"
 	"api/internal/repository"
-	"log"
+	"context"
+	"io"
+	"slices"
+	"time"
 )
 
+// Скрытый от читателя пост выглядит несуществующим, чтобы не раскрывать его наличие
+var errPostHidden = apperror.NotFound(apperror.CodePostNotFound, "Post not found")
+
+// postAudience кем читатель приходится автору постов. Определяет, какие посты
+// ему видны, и входит в ключ кэша постов автора.
+type postAudience string
+
+const (
+	audienceOwner   postAudience = "owner"
+	audienceFriends postAudience = "friends"
+	audiencePublic  postAudience = "public"
+)
+
+// visibilities возвращает видимости постов, доступные аудитории
+func (a postAudience) visibilities() []models.PostVisibility {
+	switch a {
+	case audienceOwner:
+		return []models.PostVisibility{models.VisibilityPublic, models.VisibilityFriends, models.VisibilityPrivate}
+	case audienceFriends:
+		return []models.PostVisibility{models.VisibilityPublic, models.VisibilityFriends}
+	default:
+		return []models.PostVisibility{models.VisibilityPublic}
+	}
+}
+
 type PostService struct {
-	postRepo     *repository.PostRepository
-	cacheService *CacheService
+	postRepo          *repository.PostRepository
+	friendService     *FriendService
+	cacheService      *CacheService
+	auditService      *AuditService
+	reactionService   *ReactionService
+	attachmentService *AttachmentService
+	tasks             *BackgroundTasks
+	softDelete        config.SoftDeleteConfig
 }
 
-func NewPostService(postRepo *repository.PostRepository, cacheService *CacheService) *PostService {
+func NewPostService(postRepo *repository.PostRepository, friendService *FriendService, cacheService *CacheService, auditService *AuditService, reactionService *ReactionService, attachmentService *AttachmentService, tasks *BackgroundTasks, softDelete config.SoftDeleteConfig) *PostService {
 	return &PostService{
-		postRepo:     postRepo,
-		cacheService: cacheService,
+		postRepo:          postRepo,
+		friendService:     friendService,
+		cacheService:      cacheService,
+		auditService:      auditService,
+		reactionService:   reactionService,
+		attachmentService: attachmentService,
+		tasks:             tasks,
+		softDelete:        softDelete,
 	}
 }
 
 // CreatePost создает новый пост с инвалидацией кэша
-func (s *PostService) CreatePost(userID int, req *models.CreatePostRequest) (*models.Post, error) {
+func (s *PostService) CreatePost(ctx context.Context, userID int, req *models.CreatePostRequest) (*models.Post, error) {
 	post := &models.Post{
-		UserID:  userID,
-		Title:   req.Title,
-		Content: req.Content,
+		UserID:     userID,
+		Title:      req.Title,
+		Content:    req.Content,
+		Visibility: req.Visibility,
+	}
+	if post.Visibility == "" {
+		post.Visibility = models.VisibilityPublic
 	}
 
-	err := s.postRepo.CreatePost(post)
+	err := s.postRepo.CreatePost(ctx, post)
 	if err != nil {
 		return nil, err
 	}
 
+	s.auditService.Record(ctx, AuditPostCreate, userID, AuditTargetPost, post.ID, nil)
+
 	// Инвалидируем кэш ленты друзей и постов пользователя
-	go func() {
-		if err := s.cacheService.InvalidateUserFeedCache(userID); err != nil {
-			log.Printf("Failed to invalidate feed cache for user %d: %v", userID, err)
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		if err := s.cacheService.InvalidateUserFeedCache(ctx, userID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to invalidate feed cache", "user_id", userID, logging.Err(err))
 		}
-		if err := s.cacheService.InvalidateUserPostsCache(userID); err != nil {
-			log.Printf("Failed to invalidate posts cache for user %d: %v", userID, err)
+		if err := s.cacheService.InvalidateUserPostsCache(ctx, userID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to invalidate posts cache", "user_id", userID, logging.Err(err))
 		}
-	}()
+	})
 
 	return post, nil
 }
 
-// GetPost возвращает пост по ID (без кэширования, так как редко запрашиваются по одному)
-func (s *PostService) GetPost(postID int) (*models.PostResponse, error) {
-	return s.postRepo.GetPost(postID)
+// GetPost возвращает пост по ID, если он виден пользователю viewerID
+// (без кэширования, так как редко запрашиваются по одному)
+func (s *PostService) GetPost(ctx context.Context, postID, viewerID int) (*models.PostResponse, error) {
+	post, err := s.postRepo.GetPost(ctx, postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVisible(ctx, viewerID, post.UserID, post.Visibility); err != nil {
+		return nil, err
+	}
+
+	posts := []models.PostResponse{*post}
+	if err := s.attachmentService.Fill(ctx, viewerID, posts); err != nil {
+		return nil, err
+	}
+	s.reactionService.Attach(ctx, viewerID, posts)
+	return &posts[0], nil
+}
+
+// UpdatePost обновляет пост с инвалидацией кэша и возвращает его новую версию.
+// Если expectedVersions не пустой, а версии поста в нем нет, возвращается ошибка CodePostModified.
+func (s *PostService) UpdatePost(ctx context.Context, postID, userID int, expectedVersions []int, req *models.UpdatePostRequest) (int, error) {
+	version, err := s.postRepo.UpdatePost(ctx, postID, userID, expectedVersions, req)
+	if err != nil {
+		return 0, err
+	}
+
+	s.auditService.Record(ctx, AuditPostUpdate, userID, AuditTargetPost, postID, map[string]interface{}{
+		"version": version,
+	})
+
+	// Закэшированные ленты друзей должны сразу показать новый текст, версию и признак правки,
+	// а при смене видимости - убрать пост
+	s.InvalidateAuthorPostsCaches(ctx, userID)
+
+	return version, nil
 }
 
-// UpdatePost обновляет пост с инвалидацией кэша
-func (s *PostService) UpdatePost(postID, userID int, req *models.UpdatePostRequest) error {
-	err := s.postRepo.UpdatePost(postID, userID, req)
+// GetPostRevisions возвращает предыдущие версии поста; доступно только автору
+func (s *PostService) GetPostRevisions(ctx context.Context, postID, userID int) ([]models.PostRevision, error) {
+	authorID, visibility, err := s.postRepo.GetPostVisibility(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Скрытый от пользователя пост выглядит несуществующим, как в GetPost
+	if err := s.checkVisible(ctx, userID, authorID, visibility); err != nil {
+		return nil, err
+	}
+	if authorID != userID {
+		return nil, apperror.Forbidden(apperror.CodePostForbidden, "Post belongs to another user")
+	}
+
+	return s.postRepo.GetPostRevisions(ctx, postID, userID)
+}
+
+// DeletePost помечает пост удаленным с инвалидацией кэша. Пост можно восстановить
+// в течение окна восстановления, окончательно он удаляется по сроку хранения.
+func (s *PostService) DeletePost(ctx context.Context, postID, userID int) error {
+	err := s.postRepo.DeletePost(ctx, postID, userID)
 	if err != nil {
 		return err
 	}
 
-	// Инвалидируем кэш
-	go func() {
-		if err := s.cacheService.InvalidateUserFeedCache(userID); err != nil {
-			log.Printf("Failed to invalidate feed cache for user %d: %v", userID, err)
-		}
-		if err := s.cacheService.InvalidateUserPostsCache(userID); err != nil {
-			log.Printf("Failed to invalidate posts cache for user %d: %v", userID, err)
-		}
-	}()
+	s.auditService.Record(ctx, AuditPostDelete, userID, AuditTargetPost, postID, nil)
+	// Пост должен сразу пропасть из закэшированных лент друзей
+	s.InvalidateAuthorPostsCaches(ctx, userID)
 
 	return nil
 }
 
-// DeletePost удаляет пост с инвалидацией кэша
-func (s *PostService) DeletePost(postID, userID int) error {
-	err := s.postRepo.DeletePost(postID, userID)
+// RestorePost восстанавливает удаленный пост автора, если окно восстановления не истекло
+func (s *PostService) RestorePost(ctx context.Context, postID, userID int) error {
+	err := s.postRepo.RestorePost(ctx, postID, userID, time.Now().Add(-s.softDelete.RestoreWindow))
 	if err != nil {
 		return err
 	}
 
-	// Инвалидируем кэш
-	go func() {
-		if err := s.cacheService.InvalidateUserFeedCache(userID); err != nil {
-			log.Printf("Failed to invalidate feed cache for user %d: %v", userID, err)
-		}
-		if err := s.cacheService.InvalidateUserPostsCache(userID); err != nil {
-			log.Printf("Failed to invalidate posts cache for user %d: %v", userID, err)
-		}
-	}()
+	s.auditService.Record(ctx, AuditPostRestore, userID, AuditTargetPost, postID, nil)
+	s.InvalidateAuthorPostsCaches(ctx, userID)
 
 	return nil
 }
 
-// GetUserPosts возвращает посты пользователя с кэшированием
-func (s *PostService) GetUserPosts(userID, page, pageSize int) (*models.FeedResponse, error) {
+// GetUserPosts возвращает видимые viewerID посты пользователя userID с кэшированием
+func (s *PostService) GetUserPosts(ctx context.Context, viewerID, userID, page, pageSize int) (*models.FeedResponse, error) {
+	audience, err := s.audience(ctx, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Пытаемся получить из кэша
-	if cached, err := s.cacheService.GetUserPostsFromCache(userID, page, pageSize); err == nil {
-		log.Printf("Cache HIT for user posts: user=%d, page=%d", userID, page)
+	if cached, err := s.cacheService.GetUserPostsFromCache(ctx, userID, string(audience), page, pageSize); err == nil {
+		logging.FromContext(ctx).Debug("Cache hit", "cache", "user_posts", "user_id", userID, "page", page)
+		s.attachmentService.SetURLs(viewerID, cached.Posts)
+		s.reactionService.Attach(ctx, viewerID, cached.Posts)
 		return cached, nil
 	}
 
-	log.Printf("Cache MISS for user posts: user=%d, page=%d", userID, page)
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
+	logging.FromContext(ctx).Debug("Cache miss", "cache", "user_posts", "user_id", userID, "page", page)
 
-	offset := (page - 1) * pageSize
-	posts, total, err := s.postRepo.GetUserPosts(userID, pageSize, offset)
+	page, pageSize = normalizePaging(page, pageSize)
+	feed, err := s.loadUserPosts(ctx, viewerID, userID, audience, page, pageSize)
 	if err != nil {
 		return nil, err
 	}
 
-	pages := (total + pageSize - 1) / pageSize
-
-	feed := &models.FeedResponse{
-		Posts: posts,
-		Total: total,
-		Page:  page,
-		Pages: pages,
-	}
+	// Реакции заполняются до передачи ленты в фоновую задачу, которая ее сериализует
+	s.reactionService.Attach(ctx, viewerID, feed.Posts)
 
 	// Сохраняем в кэш асинхронно
-	go func() {
-		if err := s.cacheService.SetUserPostsToCache(userID, page, pageSize, feed); err != nil {
-			log.Printf("Failed to cache user posts for user %d: %v", userID, err)
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		if err := s.cacheService.SetUserPostsToCache(ctx, userID, string(audience), page, pageSize, feed); err != nil {
+			logging.FromContext(ctx).Warn("Failed to cache user posts", "user_id", userID, logging.Err(err))
 		}
-	}()
+	})
 
 	return feed, nil
 }
 
 // GetFriendsPosts возвращает ленту постов друзей с кэшированием
-func (s *PostService) GetFriendsPosts(userID, page, pageSize int) (*models.FeedResponse, error) {
+func (s *PostService) GetFriendsPosts(ctx context.Context, userID, page, pageSize int) (*models.FeedResponse, error) {
 	// Пытаемся получить из кэша
-	if cached, err := s.cacheService.GetFeedFromCache(userID, page, pageSize); err == nil {
-		log.Printf("Cache HIT for feed: user=%d, page=%d", userID, page)
+	if cached, err := s.cacheService.GetFeedFromCache(ctx, userID, page, pageSize); err == nil {
+		logging.FromContext(ctx).Debug("Cache hit", "cache", "feed", "user_id", userID, "page", page)
+		s.attachmentService.SetURLs(userID, cached.Posts)
+		s.reactionService.Attach(ctx, userID, cached.Posts)
 		return cached, nil
 	}
 
-	log.Printf("Cache MISS for feed: user=%d, page=%d", userID, page)
+	logging.FromContext(ctx).Debug("Cache miss", "cache", "feed", "user_id", userID, "page", page)
 
-	if page < 1 {
-		page = 1
+	page, pageSize = normalizePaging(page, pageSize)
+	feed, err := s.loadFriendsPosts(ctx, userID, page, pageSize)
+	if err != nil {
+		return nil, err
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+
+	// Реакции заполняются до передачи ленты в фоновую задачу, которая ее сериализует
+	s.reactionService.Attach(ctx, userID, feed.Posts)
+
+	// Сохраняем в кэш асинхронно
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		if err := s.cacheService.SetFeedToCache(ctx, userID, page, pageSize, feed); err != nil {
+			logging.FromContext(ctx).Warn("Failed to cache feed", "user_id", userID, logging.Err(err))
+		}
+	})
+
+	return feed, nil
+}
+
+// RefreshUserPostsCache загружает страницу постов пользователя, какой ее видит он сам,
+// из БД и сохраняет ее в кэш
+func (s *PostService) RefreshUserPostsCache(ctx context.Context, userID, page, pageSize int) error {
+	page, pageSize = normalizePaging(page, pageSize)
+	feed, err := s.loadUserPosts(ctx, userID, userID, audienceOwner, page, pageSize)
+	if err != nil {
+		return err
 	}
+	return s.cacheService.SetUserPostsToCache(ctx, userID, string(audienceOwner), page, pageSize, feed)
+}
+
+// RefreshFriendsPostsCache загружает страницу ленты из БД и сохраняет ее в кэш
+func (s *PostService) RefreshFriendsPostsCache(ctx context.Context, userID, page, pageSize int) error {
+	page, pageSize = normalizePaging(page, pageSize)
+	feed, err := s.loadFriendsPosts(ctx, userID, page, pageSize)
+	if err != nil {
+		return err
+	}
+	return s.cacheService.SetFeedToCache(ctx, userID, page, pageSize, feed)
+}
 
+func (s *PostService) loadUserPosts(ctx context.Context, viewerID, userID int, audience postAudience, page, pageSize int) (*models.FeedResponse, error) {
 	offset := (page - 1) * pageSize
-	posts, total, err := s.postRepo.GetFriendsPosts(userID, pageSize, offset)
+	posts, total, err := s.postRepo.GetUserPosts(ctx, viewerID, userID, audience.visibilities(), pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.attachmentService.Fill(ctx, viewerID, posts); err != nil {
+		return nil, err
+	}
 
-	pages := (total + pageSize - 1) / pageSize
+	return &models.FeedResponse{
+		Posts: posts,
+		Total: total,
+		Page:  page,
+		Pages: (total + pageSize - 1) / pageSize,
+	}, nil
+}
 
-	feed := &models.FeedResponse{
+func (s *PostService) loadFriendsPosts(ctx context.Context, userID, page, pageSize int) (*models.FeedResponse, error) {
+	offset := (page - 1) * pageSize
+	posts, total, err := s.postRepo.GetFriendsPosts(ctx, userID, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachmentService.Fill(ctx, userID, posts); err != nil {
+		return nil, err
+	}
+
+	return &models.FeedResponse{
 		Posts: posts,
 		Total: total,
 		Page:  page,
-		Pages: pages,
+		Pages: (total + pageSize - 1) / pageSize,
+	}, nil
+}
+
+// CheckVisible возвращает ошибку "не найден", если пост не виден пользователю viewerID
+func (s *PostService) CheckVisible(ctx context.Context, postID, viewerID int) error {
+	authorID, visibility, err := s.postRepo.GetPostVisibility(ctx, postID, viewerID)
+	if err != nil {
+		return err
 	}
+	return s.checkVisible(ctx, viewerID, authorID, visibility)
+}
 
-	// Сохраняем в кэш асинхронно
-	go func() {
-		if err := s.cacheService.SetFeedToCache(userID, page, pageSize, feed); err != nil {
-			log.Printf("Failed to cache feed for user %d: %v", userID, err)
+// SetReaction ставит реакцию на видимый пользователю пост
+func (s *PostService) SetReaction(ctx context.Context, postID, userID int, reaction models.ReactionType) (*models.PostReactions, error) {
+	if err := s.CheckVisible(ctx, postID, userID); err != nil {
+		return nil, err
+	}
+	return s.reactionService.SetReaction(ctx, postID, userID, reaction)
+}
+
+// RemoveReaction снимает реакцию; снять свою реакцию можно и со скрытого поста
+func (s *PostService) RemoveReaction(ctx context.Context, postID, userID int) (*models.PostReactions, error) {
+	return s.reactionService.RemoveReaction(ctx, postID, userID)
+}
+
+// AddAttachment прикрепляет изображение к посту автора userID
+func (s *PostService) AddAttachment(ctx context.Context, postID, userID int, data []byte) (*models.Attachment, error) {
+	attachment, err := s.attachmentService.Upload(ctx, postID, userID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, AuditAttachmentAdd, userID, AuditTargetAttachment, attachment.ID, map[string]interface{}{
+		"post_id":      postID,
+		"content_type": attachment.ContentType,
+		"size":         attachment.Size,
+	})
+	s.InvalidateAuthorPostsCaches(ctx, userID)
+
+	return attachment, nil
+}
+
+// DeleteAttachment удаляет вложение по запросу автора поста
+func (s *PostService) DeleteAttachment(ctx context.Context, attachmentID, userID int) error {
+	attachment, err := s.attachmentService.Delete(ctx, attachmentID, userID)
+	if err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditAttachmentDelete, userID, AuditTargetAttachment, attachmentID, map[string]interface{}{"post_id": attachment.PostID})
+	s.InvalidateAuthorPostsCaches(ctx, userID)
+
+	return nil
+}
+
+// OpenAttachment открывает файл вложения по подписанной ссылке. Доступ проверяется
+// при каждом открытии: файлы удаленного или скрытого от читателя поста не отдаются.
+// Возвращает содержимое, его тип и время, до которого действует ссылка.
+func (s *PostService) OpenAttachment(ctx context.Context, link media.Link) (io.ReadCloser, string, time.Time, error) {
+	attachment, expiresAt, err := s.attachmentService.Resolve(ctx, link)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if err := s.CheckVisible(ctx, attachment.PostID, link.ViewerID); err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	body, contentType, err := s.attachmentService.Open(ctx, attachment, link.Thumbnail)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	return body, contentType, expiresAt, nil
+}
+
+// InvalidateAuthorPostsCaches сбрасывает в фоне закэшированные страницы с постами автора:
+// его посты и ленты его друзей
+func (s *PostService) InvalidateAuthorPostsCaches(ctx context.Context, authorID int) {
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		if err := s.cacheService.InvalidateUserPostsCache(ctx, authorID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to invalidate posts cache", "user_id", authorID, logging.Err(err))
 		}
-	}()
 
-	return feed, nil
+		friendIDs, err := s.friendService.GetFriendIDs(ctx, authorID)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to load friends for feed cache invalidation", "user_id", authorID, logging.Err(err))
+			return
+		}
+		for _, friendID := range friendIDs {
+			if err := s.cacheService.InvalidateUserFeedCache(ctx, friendID); err != nil {
+				logging.FromContext(ctx).Warn("Failed to invalidate feed cache", "user_id", friendID, logging.Err(err))
+			}
+		}
+	})
+}
+
+// audience определяет, кем viewerID приходится автору authorID
+func (s *PostService) audience(ctx context.Context, viewerID, authorID int) (postAudience, error) {
+	if viewerID == authorID {
+		return audienceOwner, nil
+	}
+
+	isFriend, err := s.friendService.IsFriend(ctx, viewerID, authorID)
+	if err != nil {
+		return "", err
+	}
+	if isFriend {
+		return audienceFriends, nil
+	}
+	return audiencePublic, nil
+}
+
+// checkVisible проверяет, что пост автора authorID с видимостью visibility виден viewerID
+func (s *PostService) checkVisible(ctx context.Context, viewerID, authorID int, visibility models.PostVisibility) error {
+	// Публичный пост виден всем без проверки дружбы
+	if visibility == models.VisibilityPublic {
+		return nil
+	}
+
+	audience, err := s.audience(ctx, viewerID, authorID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(audience.visibilities(), visibility) {
+		return errPostHidden
+	}
+	return nil
+}
+
+// normalizePaging приводит параметры пагинации к допустимым значениям
+func normalizePaging(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
 }
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"api/internal/config"
+	"api/internal/monitoring"
+	"api/internal/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PurgeService окончательно удаляет посты и пользователей, помеченные удаленными
+// раньше срока хранения, вместе с файлами вложений
+type PurgeService struct {
+	postRepo          *repository.PostRepository
+	userRepo          *repository.UserRepository
+	attachmentService *AttachmentService
+	tasks             *BackgroundTasks
+	cfg               config.SoftDeleteConfig
+}
+
+func NewPurgeService(postRepo *repository.PostRepository, userRepo *repository.UserRepository, attachmentService *AttachmentService, tasks *BackgroundTasks, cfg config.SoftDeleteConfig) *PurgeService {
+	return &PurgeService{
+		postRepo:          postRepo,
+		userRepo:          userRepo,
+		attachmentService: attachmentService,
+		tasks:             tasks,
+		cfg:               cfg,
+	}
+}
+
+// StartPurging запускает периодическое окончательное удаление.
+// При нулевом сроке хранения или интервале удаленные данные хранятся бессрочно.
+func (s *PurgeService) StartPurging(ctx context.Context) {
+	if s.cfg.Retention <= 0 || s.cfg.PurgeInterval <= 0 || s.cfg.PurgeBatchSize <= 0 {
+		return
+	}
+	s.tasks.Go(ctx, s.runPurging)
+}
+
+func (s *PurgeService) runPurging(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Purge(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		}
+	}
+}
+
+// Purge удаляет посты, затем пользователей, удаленных раньше срока хранения.
+// Срок хранения не бывает меньше окна восстановления, чтобы не удалить то,
+// что еще можно восстановить.
+func (s *PurgeService) Purge(ctx context.Context) {
+	before := time.Now().Add(-max(s.cfg.Retention, s.cfg.RestoreWindow))
+
+	s.purge(ctx, "post", before, s.postRepo.PurgeDeletedPosts)
+	s.purge(ctx, "user", before, s.userRepo.PurgeDeletedUsers)
+}
+
+// purge вызывает purgeBatch порциями, пока удаленные записи не закончатся
+func (s *PurgeService) purge(ctx context.Context, kind string, before time.Time, purgeBatch func(context.Context, time.Time, int) (int, []string, error)) {
+	var total int
+	for {
+		purged, blobKeys, err := purgeBatch(ctx, before, s.cfg.PurgeBatchSize)
+		if err != nil {
+			slog.Warn("Failed to purge deleted records", "kind", kind, "purged", total, "error", err)
+			return
+		}
+		total += purged
+		monitoring.RecordPurged(kind, purged)
+		s.attachmentService.DeleteBlobs(ctx, blobKeys...)
+
+		if purged < s.cfg.PurgeBatchSize {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		default:
+		}
+	}
+
+	if total > 0 {
+		slog.Info("Deleted records purged", "kind", kind, "purged", total, "before", before)
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"api/internal/cache"
+	"api/internal/config"
+	"api/internal/logging"
+	"api/internal/models"
+	"api/internal/monitoring"
+	"api/internal/repository"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	// Хэш неучтенных в PostgreSQL изменений счетчиков поста: реакция -> приращение
+	reactionDeltaKeyPrefix = "reactions:delta:"
+	// Множество постов, у которых есть неучтенные изменения
+	reactionDirtyKey = "reactions:dirty"
+)
+
+// ReactionService реакции на посты.
+// Реакция пользователя сразу пишется в post_reactions (у каждого пользователя своя строка),
+// а изменение счетчика - приращением в Redis. Периодически счетчики измененных постов
+// пересчитываются в post_reaction_counts и приращения удаляются, поэтому популярный пост
+// не создает очередь на блокировку строки счетчика. При чтении к сохраненным счетчикам
+// добавляются еще не сохраненные приращения.
+// Без Redis счетчик поста пересчитывается в PostgreSQL сразу после изменения реакции.
+type ReactionService struct {
+	reactionRepo *repository.ReactionRepository
+	cache        *cache.RedisCache
+	tasks        *BackgroundTasks
+	cfg          config.ReactionsConfig
+}
+
+func NewReactionService(reactionRepo *repository.ReactionRepository, redisCache *cache.RedisCache, tasks *BackgroundTasks, cfg config.ReactionsConfig) *ReactionService {
+	return &ReactionService{
+		reactionRepo: reactionRepo,
+		cache:        redisCache,
+		tasks:        tasks,
+		cfg:          cfg,
+	}
+}
+
+// SetReaction ставит или меняет реакцию пользователя на пост и возвращает счетчики поста
+func (s *ReactionService) SetReaction(ctx context.Context, postID, userID int, reaction models.ReactionType) (*models.PostReactions, error) {
+	previous, err := s.reactionRepo.SetReaction(ctx, postID, userID, reaction)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != reaction {
+		deltas := map[string]int64{string(reaction): 1}
+		if previous != "" {
+			deltas[string(previous)] = -1
+		}
+		s.applyDeltas(ctx, postID, deltas)
+		monitoring.RecordPostReaction(string(reaction), "set")
+	}
+
+	return s.GetReactions(ctx, userID, postID), nil
+}
+
+// RemoveReaction снимает реакцию пользователя с поста и возвращает счетчики поста
+func (s *ReactionService) RemoveReaction(ctx context.Context, postID, userID int) (*models.PostReactions, error) {
+	previous, err := s.reactionRepo.DeleteReaction(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != "" {
+		s.applyDeltas(ctx, postID, map[string]int64{string(previous): -1})
+		monitoring.RecordPostReaction(string(previous), "remove")
+	}
+
+	return s.GetReactions(ctx, userID, postID), nil
+}
+
+// GetReactions возвращает счетчики реакций поста для пользователя viewerID
+func (s *ReactionService) GetReactions(ctx context.Context, viewerID, postID int) *models.PostReactions {
+	reactions := s.load(ctx, viewerID, []int{postID})[postID]
+	return &reactions
+}
+
+// Attach заполняет счетчики реакций и реакцию пользователя viewerID в постах.
+// Ошибка чтения счетчиков не прерывает запрос: посты возвращаются без реакций.
+func (s *ReactionService) Attach(ctx context.Context, viewerID int, posts []models.PostResponse) {
+	if len(posts) == 0 {
+		return
+	}
+
+	postIDs := make([]int, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+
+	reactions := s.load(ctx, viewerID, postIDs)
+	for i := range posts {
+		posts[i].PostReactions = reactions[posts[i].ID]
+	}
+}
+
+func (s *ReactionService) load(ctx context.Context, viewerID int, postIDs []int) map[int]models.PostReactions {
+	counts, err := s.reactionRepo.GetCounts(ctx, viewerID, postIDs)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to load reaction counters", logging.Err(err))
+	}
+
+	mine, err := s.reactionRepo.GetUserReactions(ctx, viewerID, postIDs)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to load user reactions", "user_id", viewerID, logging.Err(err))
+	}
+
+	pending := s.pendingDeltas(ctx, postIDs)
+
+	result := make(map[int]models.PostReactions, len(postIDs))
+	for i, postID := range postIDs {
+		byType := make(map[models.ReactionType]int)
+		for reaction, count := range counts[postID] {
+			byType[reaction] += count
+		}
+		if pending != nil {
+			for reaction, delta := range pending[i] {
+				byType[models.ReactionType(reaction)] += int(delta)
+			}
+		}
+
+		total := 0
+		for reaction, count := range byType {
+			if count <= 0 {
+				delete(byType, reaction)
+				continue
+			}
+			total += count
+		}
+
+		result[postID] = models.PostReactions{
+			LikesCount: total,
+			Reactions:  byType,
+			LikedByMe:  mine[postID] != "",
+			MyReaction: mine[postID],
+		}
+	}
+	return result
+}
+
+// pendingDeltas возвращает несохраненные приращения счетчиков в порядке postIDs или nil
+func (s *ReactionService) pendingDeltas(ctx context.Context, postIDs []int) []map[string]int64 {
+	if s.cache == nil {
+		return nil
+	}
+
+	keys := make([]string, len(postIDs))
+	for i, postID := range postIDs {
+		keys[i] = reactionDeltaKey(postID)
+	}
+
+	deltas, err := s.cache.GetHashCounters(ctx, keys)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to load pending reaction counters", logging.Err(err))
+		return nil
+	}
+	return deltas
+}
+
+// applyDeltas учитывает изменение реакций в Redis, а если он недоступен - сразу в PostgreSQL.
+// Пересчет выполняется, только если счетчики в Redis не изменены: иначе изменение
+// было бы учтено дважды - в PostgreSQL и при чтении поверх него.
+func (s *ReactionService) applyDeltas(ctx context.Context, postID int, deltas map[string]int64) {
+	if s.cache != nil {
+		err := s.cache.IncrementHashFields(ctx, reactionDeltaKey(postID), deltas, reactionDirtyKey, strconv.Itoa(postID))
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, cache.ErrHashNotIncremented) {
+			logging.FromContext(ctx).Warn("Failed to mark reaction counters for flushing", "post_id", postID, logging.Err(err))
+			return
+		}
+		logging.FromContext(ctx).Warn("Failed to update reaction counters in Redis, recounting in database", "post_id", postID, logging.Err(err))
+	}
+
+	if err := s.reactionRepo.RecountReactions(ctx, []int{postID}); err != nil {
+		logging.FromContext(ctx).Warn("Failed to recount post reactions", "post_id", postID, logging.Err(err))
+	}
+}
+
+// StartRecountUserReactions в фоне пересчитывает счетчики постов, на которые реагировал
+// пользователь, после удаления или восстановления его аккаунта: реакции удаленных
+// пользователей не учитываются. С Redis посты отмечаются измененными и пересчитываются
+// при сохранении вместе с приращениями, иначе приращения учитывались бы дважды.
+func (s *ReactionService) StartRecountUserReactions(ctx context.Context, userID int) {
+	s.tasks.Go(ctx, func(ctx context.Context) {
+		postIDs, err := s.reactionRepo.GetReactedPostIDs(ctx, userID)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to get posts reacted by user", "user_id", userID, logging.Err(err))
+			return
+		}
+		if len(postIDs) == 0 {
+			return
+		}
+
+		if s.cache != nil {
+			members := make([]string, len(postIDs))
+			for i, postID := range postIDs {
+				members[i] = strconv.Itoa(postID)
+			}
+			err := s.cache.AddToSet(ctx, reactionDirtyKey, members...)
+			if err == nil {
+				return
+			}
+			logging.FromContext(ctx).Warn("Failed to mark posts for reaction recount, recounting in database", "user_id", userID, logging.Err(err))
+		}
+
+		if err := s.reactionRepo.RecountReactions(ctx, postIDs); err != nil {
+			logging.FromContext(ctx).Warn("Failed to recount reactions of user posts", "user_id", userID, logging.Err(err))
+		}
+	})
+}
+
+// StartFlushing запускает периодическое сохранение счетчиков из Redis в PostgreSQL.
+// Без Redis или при нулевом интервале не запускается: счетчики пересчитываются сразу.
+func (s *ReactionService) StartFlushing(ctx context.Context) {
+	if s.cache == nil || s.cfg.FlushInterval <= 0 {
+		return
+	}
+	s.tasks.Go(ctx, s.runFlushing)
+}
+
+func (s *ReactionService) runFlushing(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		}
+
+		s.Flush(ctx)
+	}
+}
+
+// Flush пересчитывает счетчики измененных постов порциями, пока такие посты не закончатся.
+// Посты извлекаются из множества атомарно, поэтому несколько экземпляров API не делают работу дважды.
+// Приращения удаляются после пересчета; реакция, попавшая между ними, снова отмечает пост
+// измененным и учитывается при следующем пересчете.
+func (s *ReactionService) Flush(ctx context.Context) {
+	batchSize := s.cfg.FlushBatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	for {
+		members, err := s.cache.PopFromSet(ctx, reactionDirtyKey, int64(batchSize))
+		if err != nil {
+			slog.Warn("Failed to fetch posts with changed reactions", "error", err)
+			return
+		}
+		if len(members) == 0 {
+			return
+		}
+
+		postIDs := make([]int, 0, len(members))
+		keys := make([]string, 0, len(members))
+		for _, member := range members {
+			postID, err := strconv.Atoi(member)
+			if err != nil {
+				continue
+			}
+			postIDs = append(postIDs, postID)
+			keys = append(keys, reactionDeltaKey(postID))
+		}
+
+		if err := s.reactionRepo.RecountReactions(ctx, postIDs); err != nil {
+			monitoring.RecordReactionFlush(len(postIDs), false)
+			slog.Warn("Failed to flush reaction counters", "posts", len(postIDs), "error", err)
+			s.requeue(ctx, members)
+			return
+		}
+		monitoring.RecordReactionFlush(len(postIDs), true)
+
+		if err := s.cache.DeleteKeys(ctx, keys); err != nil {
+			// Иначе приращения будут учитываться поверх пересчитанных счетчиков
+			slog.Warn("Failed to clear flushed reaction counters", "posts", len(postIDs), "error", err)
+			s.requeue(ctx, members)
+			return
+		}
+
+		if len(members) < batchSize {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.tasks.Stopping():
+			return
+		default:
+		}
+	}
+}
+
+// requeue возвращает посты в множество измененных, чтобы пересчитать их в следующий раз
+func (s *ReactionService) requeue(ctx context.Context, members []string) {
+	if err := s.cache.AddToSet(ctx, reactionDirtyKey, members...); err != nil {
+		slog.Error("Failed to requeue posts with changed reactions", "posts", len(members), "error", err)
+	}
+}
+
+func reactionDeltaKey(postID int) string {
+	return reactionDeltaKeyPrefix + strconv.Itoa(postID)
+}
@@ -1,10 +1,14 @@
 package service
 
 import (
+	"api/internal/apperror"
+	"api/internal/config"
+	"api/internal/database"
 	"api/internal/models"
 	"api/internal/monitoring"
 	"api/internal/repository"
 	"api/pkg/utils"
+	"context"
 	"errors"
 	"time"
 
@@ -12,56 +16,161 @@ import (
 )
 
 type UserService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret string
+	userRepo        *repository.UserRepository
+	postService     *PostService
+	reactionService *ReactionService
+	auditService    *AuditService
+	jwtSecret       string
+	softDelete      config.SoftDeleteConfig
+	accountStatuses *accountStatusCache
 }
 
-func NewUserService(userRepo *repository.UserRepository, jwtSecret string) *UserService {
+func NewUserService(userRepo *repository.UserRepository, postService *PostService, reactionService *ReactionService, auditService *AuditService, jwtSecret string, softDelete config.SoftDeleteConfig) *UserService {
 	return &UserService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:        userRepo,
+		postService:     postService,
+		reactionService: reactionService,
+		auditService:    auditService,
+		jwtSecret:       jwtSecret,
+		softDelete:      softDelete,
+		accountStatuses: newAccountStatusCache(AccountStatusCacheTTL),
 	}
 }
 
-func (s *UserService) Register(user *models.User) error {
+// Register регистрирует пользователя; данные проверяются заранее (validation.User)
+func (s *UserService) Register(ctx context.Context, user *models.User) error {
 	// Check if user already exists
-	exists, err := s.userRepo.UserExists(user.Username, user.Email)
+	exists, err := s.userRepo.UserExists(ctx, user.Username, user.Email)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return errors.New("user with this username or email already exists")
-	}
-
-	// Validate birth date format
-	if _, err := time.Parse("2006-01-02", user.BirthDate); err != nil {
-		return errors.New("invalid birth date format, expected YYYY-MM-DD")
+		return apperror.Conflict(apperror.CodeUserAlreadyExists, "User with this username or email already exists")
 	}
 
 	if err == nil {
 		monitoring.RecordUserRegistration()
 	}
 
-	return s.userRepo.CreateUser(user)
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditUserRegister, user.ID, AuditTargetUser, user.ID, nil)
+	return nil
+}
+
+func (s *UserService) Login(ctx context.Context, loginReq *models.LoginRequest) (*models.AuthResponse, error) {
+	user, err := s.authenticate(ctx, loginReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Удаленный аккаунт не входит, пока его не восстановят через RestoreAccount
+	if user.DeletedAt != nil {
+		s.auditService.Record(ctx, AuditUserLoginFailed, 0, AuditTargetUser, user.ID, map[string]interface{}{
+			"email":  loginReq.Email,
+			"reason": "account_deleted",
+		})
+		return nil, apperror.Forbidden(apperror.CodeAccountDeleted, "Account is deleted")
+	}
+
+	monitoring.RecordUserLogin(true)
+	s.auditService.Record(ctx, AuditUserLogin, user.ID, AuditTargetUser, user.ID, nil)
+
+	return s.authResponse(user)
+}
+
+// DeleteAccount помечает аккаунт пользователя удаленным. Профиль и посты сразу скрываются,
+// аккаунт можно восстановить в течение окна восстановления, а по сроку хранения
+// он удаляется окончательно вместе со всеми данными.
+func (s *UserService) DeleteAccount(ctx context.Context, userID int) error {
+	if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	s.accountStatuses.Set(userID, false)
+
+	s.auditService.Record(ctx, AuditUserDelete, userID, AuditTargetUser, userID, nil)
+	s.postService.InvalidateAuthorPostsCaches(ctx, userID)
+	s.reactionService.StartRecountUserReactions(ctx, userID)
+
+	return nil
+}
+
+// RestoreAccount восстанавливает удаленный аккаунт по учетным данным и выполняет вход
+func (s *UserService) RestoreAccount(ctx context.Context, loginReq *models.LoginRequest) (*models.AuthResponse, error) {
+	user, err := s.authenticate(ctx, loginReq)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt == nil {
+		return nil, apperror.Conflict(apperror.CodeAccountNotDeleted, "Account is not deleted")
+	}
+
+	if err := s.userRepo.RestoreUser(ctx, user.ID, time.Now().Add(-s.softDelete.RestoreWindow)); err != nil {
+		return nil, err
+	}
+	s.accountStatuses.Invalidate(user.ID)
+
+	s.auditService.Record(ctx, AuditUserRestore, user.ID, AuditTargetUser, user.ID, nil)
+	s.postService.InvalidateAuthorPostsCaches(ctx, user.ID)
+	s.reactionService.StartRecountUserReactions(ctx, user.ID)
+
+	return s.authResponse(user)
 }
 
-func (s *UserService) Login(loginReq *models.LoginRequest) (*models.AuthResponse, error) {
-	user, err := s.userRepo.GetUserByEmail(loginReq.Email)
+// CheckActive возвращает ошибку авторизации, если аккаунт удален: выданные
+// до удаления токены перестают действовать. Результат кэшируется в памяти
+// на AccountStatusCacheTTL, удаление и восстановление на этом экземпляре
+// учитываются сразу.
+func (s *UserService) CheckActive(ctx context.Context, userID int) error {
+	active, ok := s.accountStatuses.Get(userID)
+	if !ok {
+		var err error
+		if active, err = s.userRepo.IsActive(ctx, userID); err != nil {
+			return err
+		}
+		s.accountStatuses.Set(userID, active)
+	}
+	if !active {
+		return apperror.Unauthorized(apperror.CodeAccountDeleted, "Account is deleted")
+	}
+	return nil
+}
+
+// authenticate находит пользователя по почте, в том числе удаленного, и проверяет пароль
+func (s *UserService) authenticate(ctx context.Context, loginReq *models.LoginRequest) (*models.User, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, loginReq.Email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		// Отмену и таймаут запроса не выдаем за неверные учетные данные
+		if ctx.Err() != nil || database.IsTimeout(err) {
+			return nil, err
+		}
+		s.auditService.Record(ctx, AuditUserLoginFailed, 0, "", 0, map[string]interface{}{
+			"email":  loginReq.Email,
+			"reason": "unknown_email",
+		})
+		return nil, apperror.Unauthorized(apperror.CodeInvalidCredentials, "Invalid credentials")
 	}
 
 	if !utils.CheckPasswordHash(loginReq.Password, user.Password) {
-		return nil, errors.New("invalid credentials")
+		s.auditService.Record(ctx, AuditUserLoginFailed, 0, AuditTargetUser, user.ID, map[string]interface{}{
+			"email":  loginReq.Email,
+			"reason": "wrong_password",
+		})
+		return nil, apperror.Unauthorized(apperror.CodeInvalidCredentials, "Invalid credentials")
 	}
 
+	return user, nil
+}
+
+// authResponse выпускает токен для пользователя
+func (s *UserService) authResponse(user *models.User) (*models.AuthResponse, error) {
 	token, err := s.generateJWT(user.ID, user.Email)
 	if err != nil {
 		return nil, err
 	}
 
-	monitoring.RecordUserLogin(err == nil)
-
 	userResponse := models.UserResponse{
 		ID:        user.ID,
 		Username:  user.Username,
@@ -81,24 +190,26 @@ func (s *UserService) Login(loginReq *models.LoginRequest) (*models.AuthResponse
 	}, nil
 }
 
-func (s *UserService) GetUserByID(id int) (*models.UserResponse, error) {
-	return s.userRepo.GetUserByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id int) (*models.UserResponse, error) {
+	return s.userRepo.GetUserByID(ctx, id)
 }
 
-func (s *UserService) GetAllUsers() ([]models.UserResponse, error) {
-	return s.userRepo.GetAllUsers()
+func (s *UserService) GetAllUsers(ctx context.Context) ([]models.UserResponse, error) {
+	return s.userRepo.GetAllUsers(ctx)
 }
 
-// func (s *UserService) UpdateUser(id int, updateReq *models.UpdateUserRequest) error {
-// 	// Validate birth date if provided
-// 	if updateReq.BirthDate != nil {
-// 		if _, err := time.Parse("2006-01-02", *updateReq.BirthDate); err != nil {
-// 			return errors.New("invalid birth date format, expected YYYY-MM-DD")
-// 		}
-// 	}
+// UpdateUser обновляет профиль пользователя; в журнал аудита пишутся измененные поля.
+// Поля проверяются заранее (validation.UpdateUser).
+func (s *UserService) UpdateUser(ctx context.Context, id int, updateReq *models.UpdateUserRequest) error {
+	if err := s.userRepo.UpdateUser(ctx, id, updateReq); err != nil {
+		return err
+	}
 
-// 	return s.userRepo.UpdateUser(id, updateReq)
-// }
+	s.auditService.Record(ctx, AuditProfileUpdate, id, AuditTargetUser, id, map[string]interface{}{
+		"fields": updateReq.ChangedFields(),
+	})
+	return nil
+}
 
 func (s *UserService) generateJWT(userID int, email string) (string, error) {
 	claims := jwt.MapClaims{
@@ -131,28 +242,12 @@ func (s *UserService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 }
 
 // SearchUsers поиск пользователей
-func (s *UserService) SearchUsers(firstName, lastName string) ([]models.UserResponse, error) {
-	if firstName == "" || lastName == "" {
-		return nil, errors.New("first name and last name are required")
-	}
-
-	if len(firstName) < 2 || len(lastName) < 2 {
-		return nil, errors.New("search query must be at least 2 characters long")
-	}
-
-	return s.userRepo.SearchUsers(firstName, lastName)
+func (s *UserService) SearchUsers(ctx context.Context, firstName, lastName string) ([]models.UserResponse, error) {
+	return s.userRepo.SearchUsers(ctx, firstName, lastName)
 }
 
 // SearchUsersWithPaging поиск пользователей с пагинацией
-func (s *UserService) SearchUsersWithPaging(firstName, lastName string, page, pageSize int) (*models.UserSearchResponse, error) {
-	if firstName == "" || lastName == "" {
-		return nil, errors.New("first name and last name are required")
-	}
-
-	if len(firstName) < 2 || len(lastName) < 2 {
-		return nil, errors.New("search query must be at least 2 characters long")
-	}
-
+func (s *UserService) SearchUsersWithPaging(ctx context.Context, firstName, lastName string, page, pageSize int) (*models.UserSearchResponse, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -162,7 +257,7 @@ func (s *UserService) SearchUsersWithPaging(firstName, lastName string, page, pa
 
 	offset := (page - 1) * pageSize
 
-	users, total, err := s.userRepo.SearchUsersWithPaging(firstName, lastName, pageSize, offset)
+	users, total, err := s.userRepo.SearchUsersWithPaging(ctx, firstName, lastName, pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package service
 import (
 	"api/internal/cache"
 	"api/internal/models"
+	"context"
 	"fmt"
 )
 
@@ -19,15 +20,15 @@ func NewVersionedCacheService(redisCache *cache.RedisCache) *VersionedCacheServi
 }
 
 // GetFeedFromCache получает ленту из кэша с проверкой версии
-func (v *VersionedCacheService) GetFeedFromCache(userID, page, pageSize int) (*models.FeedResponse, error) {
+func (v *VersionedCacheService) GetFeedFromCache(ctx context.Context, userID, page, pageSize int) (*models.FeedResponse, error) {
 	baseKey := fmt.Sprintf("feed:user:%d:page:%d:size:%d", userID, page, pageSize)
-	versionedKey, err := v.version.GenerateVersionedKey(baseKey, userID)
+	versionedKey, err := v.version.GenerateVersionedKey(ctx, baseKey, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	var feed models.FeedResponse
-	if err := v.cache.Get(versionedKey, &feed); err != nil {
+	if err := v.cache.Get(ctx, versionedKey, &feed); err != nil {
 		return nil, err
 	}
 
@@ -35,18 +36,18 @@ func (v *VersionedCacheService) GetFeedFromCache(userID, page, pageSize int) (*m
 }
 
 // SetFeedToCache сохраняет ленту в кэш с версией
-func (v *VersionedCacheService) SetFeedToCache(userID, page, pageSize int, feed *models.FeedResponse) error {
+func (v *VersionedCacheService) SetFeedToCache(ctx context.Context, userID, page, pageSize int, feed *models.FeedResponse) error {
 	baseKey := fmt.Sprintf("feed:user:%d:page:%d:size:%d", userID, page, pageSize)
-	versionedKey, err := v.version.GenerateVersionedKey(baseKey, userID)
+	versionedKey, err := v.version.GenerateVersionedKey(ctx, baseKey, userID)
 	if err != nil {
 		return err
 	}
 
-	return v.cache.Set(versionedKey, feed, FeedCacheTTL)
+	return v.cache.Set(ctx, versionedKey, feed, FeedCacheTTL)
 }
 
 // InvalidateUserCache инвалидирует ВСЕ кэши пользователя через смену версии
-func (v *VersionedCacheService) InvalidateUserCache(userID int) error {
-	_, err := v.version.IncrementCacheVersion(userID)
+func (v *VersionedCacheService) InvalidateUserCache(ctx context.Context, userID int) error {
+	_, err := v.version.IncrementCacheVersion(ctx, userID)
 	return err
 }
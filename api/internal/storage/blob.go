@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"api/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrBlobNotFound объекта с таким ключом нет в хранилище
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore хранилище файлов вложений.
+// Ключи - относительные пути через "/", например "posts/1/<uuid>.jpg".
+type BlobStore interface {
+	// Put сохраняет объект размером size байт, перезаписывая существующий
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get открывает объект для чтения и возвращает его тип содержимого
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	// Delete удаляет объект; отсутствие объекта не ошибка
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobStore создает хранилище, выбранное в MEDIA_STORE.
+// Клиенты не обращаются к хранилищу напрямую: файлы отдает API.
+func NewBlobStore(cfg config.MediaConfig) (BlobStore, error) {
+	switch cfg.Store {
+	case "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown media store %q", cfg.Store)
+	}
+}
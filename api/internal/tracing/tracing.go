@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"api/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "api"
+
+// Init настраивает распространение контекста трассировки (W3C trace-context и baggage)
+// и, если трассировка включена, экспорт спанов по OTLP/HTTP.
+// Адрес коллектора, заголовки и TLS задаются стандартными переменными OTEL_EXPORTER_OTLP_*.
+// Возвращает функцию, отправляющую оставшиеся спаны при остановке.
+func Init(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	// Контекст распространяется и при выключенной трассировке,
+	// чтобы trace ID из входящих запросов попадал в логи и ответы
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	slog.Info("Tracing enabled", "service", cfg.ServiceName, "sample_ratio", cfg.SampleRatio)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трассировщик приложения
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// StartChild начинает дочерний спан, только если в контексте уже есть спан.
+// Фоновые операции без родителя (проверки соединений и т.п.) не создают отдельных трасс.
+func StartChild(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, parent
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// TraceID возвращает ID трассы из контекста или пустую строку
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
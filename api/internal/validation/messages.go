@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"api/internal/models"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Поддерживаемые языки сообщений
+const (
+	LocaleEN = "en"
+	LocaleRU = "ru"
+)
+
+// Первый язык используется, если клиент не указал поддерживаемый
+var localeMatcher = language.NewMatcher([]language.Tag{language.English, language.Russian})
+
+var summaries = map[string]string{
+	LocaleEN: "Request validation failed",
+	LocaleRU: "Запрос не прошел проверку",
+}
+
+var messages = map[string]map[string]string{
+	LocaleEN: {
+		RuleRequired:       "Field is required",
+		RuleTooShort:       "Must be at least {min} characters",
+		RuleTooLong:        "Must be at most {max} characters",
+		RuleTooManyBytes:   "Must be at most {max} bytes in UTF-8; non-Latin letters take 2 bytes, emoji take 4",
+		RuleInvalidCharset: "Contains invalid characters",
+		RuleInvalidEmail:   "Must be a valid email address",
+		RuleWeakPassword:   "Must contain at least one letter and one digit",
+		RuleInvalidDate:    "Must be a date in YYYY-MM-DD format",
+		RuleDateOutOfRange: "Must be between {min} and {max}",
+		RuleNotAllowed:     "Must be one of: {values}",
+		RuleNotPositive:    "Must be a positive number",
+		RuleEmptyUpdate:    "At least one field must be provided",
+		RuleFileTooLarge:   "File must not exceed {max}",
+		RuleInvalidImage:   "File is not a valid image",
+		RuleImageTooLarge:  "Image must not exceed {max} megapixels",
+	},
+	LocaleRU: {
+		RuleRequired:       "Обязательное поле",
+		RuleTooShort:       "Должно содержать не менее {min} символов",
+		RuleTooLong:        "Должно содержать не более {max} символов",
+		RuleTooManyBytes:   "Должно занимать не более {max} байт в UTF-8; кириллица занимает 2 байта на букву, эмодзи - 4",
+		RuleInvalidCharset: "Содержит недопустимые символы",
+		RuleInvalidEmail:   "Некорректный адрес электронной почты",
+		RuleWeakPassword:   "Должен содержать хотя бы одну букву и одну цифру",
+		RuleInvalidDate:    "Дата должна быть в формате ГГГГ-ММ-ДД",
+		RuleDateOutOfRange: "Дата должна быть в диапазоне от {min} до {max}",
+		RuleNotAllowed:     "Допустимые значения: {values}",
+		RuleNotPositive:    "Должно быть положительным числом",
+		RuleEmptyUpdate:    "Нужно передать хотя бы одно поле",
+		RuleFileTooLarge:   "Размер файла не должен превышать {max}",
+		RuleInvalidImage:   "Файл не является корректным изображением",
+		RuleImageTooLarge:  "Изображение не должно превышать {max} мегапикселей",
+	},
+}
+
+// NegotiateLocale выбирает язык сообщений по заголовку Accept-Language
+func NegotiateLocale(acceptLanguage string) string {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, _ := localeMatcher.Match(tags...)
+	if index == 1 {
+		return LocaleRU
+	}
+	return LocaleEN
+}
+
+// Summary общий текст ошибки валидации на языке locale
+func Summary(locale string) string {
+	return summaries[locale]
+}
+
+// Localize переводит ошибки полей на язык locale
+func (e Errors) Localize(locale string) []models.FieldProblem {
+	catalog, ok := messages[locale]
+	if !ok {
+		catalog = messages[LocaleEN]
+	}
+
+	problems := make([]models.FieldProblem, 0, len(e))
+	for _, fieldErr := range e {
+		message := catalog[fieldErr.Rule]
+		for key, value := range fieldErr.Params {
+			message = strings.ReplaceAll(message, "{"+key+"}", value)
+		}
+		problems = append(problems, models.FieldProblem{
+			Field:   fieldErr.Field,
+			Code:    fieldErr.Rule,
+			Message: message,
+		})
+	}
+	return problems
+}
@@ -0,0 +1,278 @@
+package validation
+
+import (
+	"api/internal/media"
+	"api/internal/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode"
+)
+
+// Ограничения полей. Максимальные длины не больше размеров колонок в таблицах.
+const (
+	usernameMinLength = 3
+	usernameMaxLength = 32
+	emailMaxLength    = 255
+	passwordMinLength = 8
+	// bcrypt не принимает пароли длиннее 72 байт
+	passwordMaxBytes   = 72
+	nameMaxLength      = 100
+	cityMaxLength      = 100
+	interestsMaxLength = 1000
+	titleMaxLength     = 255
+	contentMaxLength   = 10000
+	commentMaxLength   = 2000
+	searchMinLength    = 2
+	searchMaxLength    = 100
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+	// Буквы любого алфавита, пробел, дефис и апостроф
+	namePattern = regexp.MustCompile(`^[\p{L}][\p{L} '-]*$`)
+
+	minBirthDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// User проверяет данные регистрации
+func User(user *models.User) error {
+	var v Validator
+
+	v.Required("username", user.Username)
+	v.Length("username", user.Username, usernameMinLength, usernameMaxLength)
+	v.Match("username", user.Username, usernamePattern)
+
+	v.Required("email", user.Email)
+	v.Length("email", user.Email, 0, emailMaxLength)
+	v.Email("email", user.Email)
+
+	v.Required("password", user.Password)
+	v.Length("password", user.Password, passwordMinLength, 0)
+	v.Check(len(user.Password) <= passwordMaxBytes, "password", RuleTooManyBytes, "max", strconv.Itoa(passwordMaxBytes))
+	v.Check(strongPassword(user.Password), "password", RuleWeakPassword)
+
+	name(&v, "first_name", user.FirstName)
+	name(&v, "last_name", user.LastName)
+
+	v.Required("birth_date", user.BirthDate)
+	birthDate(&v, user.BirthDate)
+
+	v.Required("gender", string(user.Gender))
+	gender(&v, user.Gender)
+
+	v.Length("interests", user.Interests, 0, interestsMaxLength)
+	v.Length("city", user.City, 0, cityMaxLength)
+
+	return v.Err()
+}
+
+// UpdateUser проверяет переданные поля профиля; хотя бы одно поле обязательно
+func UpdateUser(req *models.UpdateUserRequest) error {
+	var v Validator
+
+	if len(req.ChangedFields()) == 0 {
+		v.Add("", RuleEmptyUpdate)
+	}
+	if req.FirstName != nil {
+		name(&v, "first_name", *req.FirstName)
+	}
+	if req.LastName != nil {
+		name(&v, "last_name", *req.LastName)
+	}
+	if req.BirthDate != nil {
+		birthDate(&v, *req.BirthDate)
+	}
+	if req.Gender != nil {
+		gender(&v, *req.Gender)
+	}
+	if req.Interests != nil {
+		v.Length("interests", *req.Interests, 0, interestsMaxLength)
+	}
+	if req.City != nil {
+		v.Length("city", *req.City, 0, cityMaxLength)
+	}
+
+	return v.Err()
+}
+
+// Login проверяет запрос входа
+func Login(req *models.LoginRequest) error {
+	var v Validator
+
+	v.Required("email", req.Email)
+	v.Email("email", req.Email)
+	v.Required("password", req.Password)
+
+	return v.Err()
+}
+
+// CreatePost проверяет новый пост
+func CreatePost(req *models.CreatePostRequest) error {
+	var v Validator
+
+	v.Required("title", req.Title)
+	v.Length("title", req.Title, 0, titleMaxLength)
+	v.Required("content", req.Content)
+	v.Length("content", req.Content, 0, contentMaxLength)
+	if req.Visibility != "" {
+		visibility(&v, req.Visibility)
+	}
+
+	return v.Err()
+}
+
+// UpdatePost проверяет изменение поста; хотя бы одно поле обязательно
+func UpdatePost(req *models.UpdatePostRequest) error {
+	var v Validator
+
+	if req.Title == nil && req.Content == nil && req.Visibility == nil {
+		v.Add("", RuleEmptyUpdate)
+	}
+	if req.Title != nil {
+		v.Required("title", *req.Title)
+		v.Length("title", *req.Title, 0, titleMaxLength)
+	}
+	if req.Content != nil {
+		v.Required("content", *req.Content)
+		v.Length("content", *req.Content, 0, contentMaxLength)
+	}
+	if req.Visibility != nil {
+		visibility(&v, *req.Visibility)
+	}
+
+	return v.Err()
+}
+
+// CreateComment проверяет новый комментарий
+func CreateComment(req *models.CreateCommentRequest) error {
+	var v Validator
+
+	v.Required("content", req.Content)
+	v.Length("content", req.Content, 0, commentMaxLength)
+	if req.ParentID != nil {
+		v.Positive("parent_id", *req.ParentID)
+	}
+
+	return v.Err()
+}
+
+// UpdateComment проверяет изменение комментария
+func UpdateComment(req *models.UpdateCommentRequest) error {
+	var v Validator
+
+	v.Required("content", req.Content)
+	v.Length("content", req.Content, 0, commentMaxLength)
+
+	return v.Err()
+}
+
+// Friend проверяет запрос добавления или удаления друга
+func Friend(req *models.FriendRequest) error {
+	var v Validator
+	v.Positive("friend_id", req.FriendID)
+	return v.Err()
+}
+
+// UserSearch проверяет параметры поиска пользователей
+func UserSearch(req *models.UserSearchRequest) error {
+	var v Validator
+
+	v.Required("first_name", req.FirstName)
+	v.Length("first_name", req.FirstName, searchMinLength, searchMaxLength)
+	v.Required("last_name", req.LastName)
+	v.Length("last_name", req.LastName, searchMinLength, searchMaxLength)
+
+	return v.Err()
+}
+
+// Reaction проверяет вид реакции на пост
+func Reaction(req *models.ReactionRequest) error {
+	var v Validator
+
+	allowed := make([]string, 0, len(models.ReactionTypes))
+	for _, reaction := range models.ReactionTypes {
+		allowed = append(allowed, string(reaction))
+	}
+	v.OneOf("reaction", string(req.Reaction), allowed...)
+
+	return v.Err()
+}
+
+// Attachment проверяет размер загружаемого файла и его тип, определенный по содержимому
+func Attachment(size, maxSize int64, contentType string) error {
+	var v Validator
+
+	v.Check(size > 0, "file", RuleRequired)
+	if !v.Failed("file") {
+		v.Check(size <= maxSize, "file", RuleFileTooLarge, "max", formatSize(maxSize))
+	}
+	v.OneOf("file", contentType, media.AllowedTypes...)
+
+	return v.Err()
+}
+
+// Image переводит ошибку разбора изображения в ошибку поля file.
+// Остальные ошибки возвращаются без изменений.
+func Image(err error) error {
+	var v Validator
+
+	switch {
+	case errors.Is(err, media.ErrInvalidImage):
+		v.Add("file", RuleInvalidImage)
+	case errors.Is(err, media.ErrImageTooLarge):
+		v.Add("file", RuleImageTooLarge, "max", strconv.Itoa(media.MaxPixels/1_000_000))
+	default:
+		return err
+	}
+
+	return v.Err()
+}
+
+func name(v *Validator, field, value string) {
+	v.Required(field, value)
+	v.Length(field, value, 0, nameMaxLength)
+	v.Match(field, value, namePattern)
+}
+
+func birthDate(v *Validator, value string) {
+	v.Date("birth_date", value, minBirthDate, time.Now().UTC())
+}
+
+func gender(v *Validator, value models.Gender) {
+	v.OneOf("gender", string(value),
+		string(models.GenderMale), string(models.GenderFemale), string(models.GenderUnknown))
+}
+
+func visibility(v *Validator, value models.PostVisibility) {
+	v.OneOf("visibility", string(value),
+		string(models.VisibilityPublic), string(models.VisibilityFriends), string(models.VisibilityPrivate))
+}
+
+// formatSize записывает размер в байтах в мегабайтах или килобайтах
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", size>>10)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+// strongPassword требует хотя бы одну букву и одну цифру
+func strongPassword(password string) bool {
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return hasLetter && hasDigit
+}
@@ -0,0 +1,172 @@
+package validation
+
+import (
+	"api/internal/apperror"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Коды правил. Возвращаются клиенту в поле code ошибки поля и не переименовываются.
+const (
+	RuleRequired       = "required"
+	RuleTooShort       = "too_short"
+	RuleTooLong        = "too_long"
+	RuleTooManyBytes   = "too_many_bytes"
+	RuleInvalidCharset = "invalid_charset"
+	RuleInvalidEmail   = "invalid_email"
+	RuleWeakPassword   = "weak_password"
+	RuleInvalidDate    = "invalid_date"
+	RuleDateOutOfRange = "date_out_of_range"
+	RuleNotAllowed     = "not_allowed"
+	RuleNotPositive    = "not_positive"
+	RuleEmptyUpdate    = "empty_update"
+	RuleFileTooLarge   = "file_too_large"
+	RuleInvalidImage   = "invalid_image"
+	RuleImageTooLarge  = "image_too_large"
+)
+
+// DateLayout формат дат в запросах
+const DateLayout = "2006-01-02"
+
+// FieldError нарушенное правило для поля запроса.
+// Params подставляются в текст сообщения: {min}, {max}, {values}.
+type FieldError struct {
+	Field  string
+	Rule   string
+	Params map[string]string
+}
+
+// Errors ошибки проверки полей запроса
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		if fieldErr.Field == "" {
+			parts = append(parts, fieldErr.Rule)
+			continue
+		}
+		parts = append(parts, fieldErr.Field+": "+fieldErr.Rule)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Validator накапливает ошибки полей. Для каждого поля сохраняется
+// только первое нарушенное правило, остальные проверки поля пропускаются.
+type Validator struct {
+	errs   Errors
+	failed map[string]bool
+}
+
+// Add добавляет ошибку поля; params - пары ключ-значение для текста сообщения
+func (v *Validator) Add(field, rule string, params ...string) {
+	if v.failed[field] {
+		return
+	}
+	if v.failed == nil {
+		v.failed = make(map[string]bool)
+	}
+	v.failed[field] = true
+
+	fieldErr := FieldError{Field: field, Rule: rule}
+	if len(params) > 0 {
+		fieldErr.Params = make(map[string]string, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			fieldErr.Params[params[i]] = params[i+1]
+		}
+	}
+	v.errs = append(v.errs, fieldErr)
+}
+
+// Failed сообщает, есть ли уже ошибка у поля
+func (v *Validator) Failed(field string) bool {
+	return v.failed[field]
+}
+
+// Check добавляет ошибку rule, если условие ok не выполнено
+func (v *Validator) Check(ok bool, field, rule string, params ...string) {
+	if !ok {
+		v.Add(field, rule, params...)
+	}
+}
+
+// Required проверяет, что строка не пустая и состоит не только из пробелов
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, RuleRequired)
+}
+
+// Length проверяет длину строки в символах; max <= 0 - без ограничения сверху
+func (v *Validator) Length(field, value string, min, max int) {
+	if v.Failed(field) {
+		return
+	}
+	n := utf8.RuneCountInString(value)
+	switch {
+	case n < min:
+		v.Add(field, RuleTooShort, "min", strconv.Itoa(min))
+	case max > 0 && n > max:
+		v.Add(field, RuleTooLong, "max", strconv.Itoa(max))
+	}
+}
+
+// Match проверяет строку регулярным выражением
+func (v *Validator) Match(field, value string, re *regexp.Regexp) {
+	if v.Failed(field) {
+		return
+	}
+	v.Check(re.MatchString(value), field, RuleInvalidCharset)
+}
+
+// Email проверяет, что строка - одиночный адрес без имени ("user@example.com")
+func (v *Validator) Email(field, value string) {
+	if v.Failed(field) {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	v.Check(err == nil && addr.Address == value && addr.Name == "", field, RuleInvalidEmail)
+}
+
+// Date проверяет формат DateLayout и попадание даты в [min, max]
+func (v *Validator) Date(field, value string, min, max time.Time) {
+	if v.Failed(field) {
+		return
+	}
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		v.Add(field, RuleInvalidDate)
+		return
+	}
+	if date.Before(min) || date.After(max) {
+		v.Add(field, RuleDateOutOfRange, "min", min.Format(DateLayout), "max", max.Format(DateLayout))
+	}
+}
+
+// OneOf проверяет, что значение входит в список допустимых
+func (v *Validator) OneOf(field, value string, allowed ...string) {
+	if v.Failed(field) {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Add(field, RuleNotAllowed, "values", strings.Join(allowed, ", "))
+}
+
+// Positive проверяет, что число больше нуля
+func (v *Validator) Positive(field string, value int) {
+	v.Check(value > 0, field, RuleNotPositive)
+}
+
+// Err возвращает доменную ошибку валидации с ошибками полей или nil
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return apperror.Validation(apperror.CodeValidationFailed, "Request validation failed").Wrap(v.errs)
+}
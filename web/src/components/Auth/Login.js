@@ -1,5 +1,6 @@
 import React, { useState } from 'react';
 import { useAuth } from '../../context/AuthContext';
+import { errorMessage } from '../../services/api';
 
 const Login = () => {
   const [formData, setFormData] = useState({
@@ -26,7 +27,7 @@ const Login = () => {
       await login(formData);
       window.location.href = '/profile';
     } catch (error) {
-      setError(error.response?.data?.error || 'Login failed');
+      setError(errorMessage(error, 'Login failed'));
     } finally {
       setLoading(false);
     }
@@ -1,6 +1,7 @@
 import React, { useState } from 'react';
 import { useAuth } from '../../context/AuthContext';
 import './AuthForms.css'; // Создадим отдельный CSS файл для стилей
+import { errorMessage } from '../../services/api';
 
 const Register = () => {
   const [formData, setFormData] = useState({
@@ -35,7 +36,7 @@ const Register = () => {
       alert('Registration successful! Please login.');
       window.location.href = '/login';
     } catch (error) {
-      setError(error.response?.data?.error || 'Registration failed');
+      setError(errorMessage(error, 'Registration failed'));
     } finally {
       setLoading(false);
     }
@@ -1,6 +1,7 @@
 import React, { useState, useEffect } from 'react';
 import { useParams, Link } from 'react-router-dom';
 import { authService } from '../../services/auth';
+import { errorMessage } from '../../services/api';
 
 const UserDetails = () => {
   const { id } = useParams();
@@ -15,7 +16,7 @@ const UserDetails = () => {
         const userData = await authService.getUserById(id);
         setUser(userData);
       } catch (error) {
-        setError(error.response?.data?.error || 'Failed to fetch user details');
+        setError(errorMessage(error, 'Failed to fetch user details'));
       } finally {
         setLoading(false);
       }
@@ -1,6 +1,7 @@
 import React, { useState, useEffect } from 'react';
 import { Link } from 'react-router-dom';
 import { authService } from '../../services/auth';
+import { errorMessage } from '../../services/api';
 
 const UserList = () => {
   const [users, setUsers] = useState([]);
@@ -13,7 +14,7 @@ const UserList = () => {
         const usersData = await authService.getAllUsers();
         setUsers(usersData);
       } catch (error) {
-        setError(error.response?.data?.error || 'Failed to fetch users');
+        setError(errorMessage(error, 'Failed to fetch users'));
       } finally {
         setLoading(false);
       }
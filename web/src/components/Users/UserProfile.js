@@ -1,6 +1,7 @@
 import React, { useState, useEffect } from 'react';
 import { Link } from 'react-router-dom';
 import { authService } from '../../services/auth';
+import { errorMessage } from '../../services/api';
 
 const UserProfile = () => {
   const [profile, setProfile] = useState(null);
@@ -13,7 +14,7 @@ const UserProfile = () => {
         const profileData = await authService.getProfile();
         setProfile(profileData);
       } catch (error) {
-        setError(error.response?.data?.error || 'Failed to fetch profile');
+        setError(errorMessage(error, 'Failed to fetch profile'));
       } finally {
         setLoading(false);
       }
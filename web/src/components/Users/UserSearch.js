@@ -1,6 +1,7 @@
 import React, { useState } from 'react';
 import { authService } from '../../services/auth';
 import './UserSearch.css';
+import { errorMessage } from '../../services/api';
 
 const UserSearch = () => {
   const [searchData, setSearchData] = useState({
@@ -49,7 +50,7 @@ const UserSearch = () => {
         total_pages: response.total_pages || Math.ceil((response.total || 0) / pagination.page_size)
       }));
     } catch (error) {
-      setError(error.response?.data?.error || 'Ошибка при поиске пользователей');
+      setError(errorMessage(error, 'Ошибка при поиске пользователей'));
       setResults([]);
     } finally {
       setLoading(false);
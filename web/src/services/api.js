@@ -35,4 +35,16 @@ api.interceptors.response.use(
   }
 );
 
-export default api;
\ No newline at end of file
+// Текст ошибки из ответа API (application/problem+json).
+// Для ошибок валидации (422) перечисляются ошибки полей.
+export const errorMessage = (error, fallback) => {
+  const problem = error.response?.data;
+  if (problem?.errors?.length) {
+    return problem.errors
+      .map((fieldError) => (fieldError.field ? `${fieldError.field}: ${fieldError.message}` : fieldError.message))
+      .join('; ');
+  }
+  return problem?.detail || fallback;
+};
+
+export default api;